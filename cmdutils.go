@@ -3,10 +3,14 @@
 package cmdutils
 
 import (
+	"context"
 	"fmt"
+	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
 	"github.com/loopholelabs/logging/types"
 
@@ -20,14 +24,25 @@ type Helper[T config.Config] struct {
 	// Config contains globally sourced configuration
 	Config T
 
-	// Printer is used to print output of a command to stdout.
-	Printer *printer.Printer
+	// Printer is used to print output of a command to stdout. Tests can
+	// replace it with a printertest.Recorder.
+	Printer printer.Interface
 
 	// Logger is used to provide structured logging for a command.
 	Logger types.RootLogger
 
 	// debug defines the debug mode
 	debug *bool
+
+	// assumeYes defines whether prompts are answered automatically
+	assumeYes *bool
+
+	// outputs holds the output type declared by each command
+	outputs map[*cobra.Command]reflect.Type
+
+	// shutdownHooks holds the hooks registered with OnShutdown
+	shutdownMu    sync.Mutex
+	shutdownHooks []func(ctx context.Context) error
 }
 
 func (h *Helper[T]) SetDebug(debug *bool) {
@@ -36,6 +51,79 @@ func (h *Helper[T]) SetDebug(debug *bool) {
 
 func (h *Helper[T]) Debug() bool { return *h.debug }
 
+func (h *Helper[T]) SetAssumeYes(assumeYes *bool) {
+	h.assumeYes = assumeYes
+}
+
+// AssumeYes returns whether the user asked to proceed without confirmation
+// prompts.
+func (h *Helper[T]) AssumeYes() bool { return *h.assumeYes }
+
+// SetOutput declares that cmd prints resources of type t.
+func (h *Helper[T]) SetOutput(cmd *cobra.Command, t reflect.Type) {
+	if h.outputs == nil {
+		h.outputs = make(map[*cobra.Command]reflect.Type)
+	}
+	h.outputs[cmd] = t
+}
+
+// Output returns the output type declared for cmd, if any.
+func (h *Helper[T]) Output(cmd *cobra.Command) (reflect.Type, bool) {
+	t, ok := h.outputs[cmd]
+	return t, ok
+}
+
+// DeclareOutput declares that cmd prints resources of type O, so that its
+// JSON Schema is included in the output of the schema command.
+func DeclareOutput[O any, T config.Config](ch *Helper[T], cmd *cobra.Command) {
+	ch.SetOutput(cmd, reflect.TypeOf((*O)(nil)).Elem())
+}
+
+// SecretFlagAnnotation marks flags whose values must not be echoed when the
+// user is prompted for them.
+const SecretFlagAnnotation = "cmdutils_secret"
+
+// MarkFlagSecret marks the flag with the given name as secret, so that its
+// value is masked when the user is prompted for it.
+func MarkFlagSecret(flags *pflag.FlagSet, name string) error {
+	return flags.SetAnnotation(name, SecretFlagAnnotation, []string{"true"})
+}
+
+// SkipValidationAnnotation marks commands, and their subcommands, that run
+// without validating the configuration or requiring the global required
+// flags.
+const SkipValidationAnnotation = "cmdutils_skip_validation"
+
+// SkipValidation makes cmd and its subcommands run without calling
+// Config.Validate or requiring the flags marked by
+// Config.GlobalRequiredFlags, for commands such as "version" or
+// "config init" that must work before the configuration is valid.
+func SkipValidation(cmd *cobra.Command) {
+	if cmd.Annotations == nil {
+		cmd.Annotations = make(map[string]string)
+	}
+	cmd.Annotations[SkipValidationAnnotation] = "true"
+}
+
+// MissingArgsError is returned by RequiredArgs when positional arguments are
+// missing.
+type MissingArgsError struct {
+	// Missing contains the names of the missing arguments
+	Missing []string
+
+	// Usage is the usage string of the command
+	Usage string
+}
+
+func (e *MissingArgsError) Error() string {
+	a := fmt.Sprintf("arguments <%s>", strings.Join(e.Missing, ", "))
+	if len(e.Missing) == 1 {
+		a = fmt.Sprintf("argument <%s>", e.Missing[0])
+	}
+
+	return fmt.Sprintf("missing %s \n\n%s", a, e.Usage)
+}
+
 // RequiredArgs - required arguments are not available.
 func RequiredArgs(reqArgs ...string) cobra.PositionalArgs {
 	return func(cmd *cobra.Command, args []string) error {
@@ -44,13 +132,9 @@ func RequiredArgs(reqArgs ...string) cobra.PositionalArgs {
 			return nil
 		}
 
-		missing := reqArgs[len(args):]
-
-		a := fmt.Sprintf("arguments <%s>", strings.Join(missing, ", "))
-		if len(missing) == 1 {
-			a = fmt.Sprintf("argument <%s>", missing[0])
+		return &MissingArgsError{
+			Missing: reqArgs[len(args):],
+			Usage:   cmd.UsageString(),
 		}
-
-		return fmt.Errorf("missing %s \n\n%s", a, cmd.UsageString())
 	}
 }
@@ -2,9 +2,40 @@
 
 package cmdutils
 
+import (
+	"context"
+	"errors"
+)
+
 const ActionRequestedExitCode = 1
 const FatalErrExitCode = 2
 
+// InvalidInputExitCode is returned when the configuration fails validation.
+const InvalidInputExitCode = 3
+
+// TimeoutExitCode is returned when a command doesn't finish within the
+// duration set by the --timeout flag.
+const TimeoutExitCode = 4
+
+// PanicExitCode is returned when a command panics.
+const PanicExitCode = 5
+
+// CanceledExitCode is returned when a command is canceled, matching the exit
+// status of a process interrupted by SIGINT.
+const CanceledExitCode = 130
+
+// TerminatedExitCode is returned when a command is stopped by SIGTERM.
+const TerminatedExitCode = 143
+
+// ErrCanceled can be returned by a command that was canceled by the user.
+var ErrCanceled = errors.New("canceled")
+
+// IsCanceled reports whether err is caused by the user or the command
+// context canceling the command, including interrupted prompts.
+func IsCanceled(err error) bool {
+	return errors.Is(err, ErrCanceled) || errors.Is(err, context.Canceled)
+}
+
 // Error can be used by a command to change the exit status of the CLI.
 type Error struct {
 	Msg string
@@ -7,25 +7,26 @@ import (
 	"errors"
 	"fmt"
 	"io"
-	"log"
 	"os"
 	"path"
 	"path/filepath"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/fatih/color"
+	"github.com/mattn/go-colorable"
 	"github.com/mitchellh/mapstructure"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 
-	"github.com/loopholelabs/logging"
 	"github.com/loopholelabs/logging/types"
 
 	"github.com/loopholelabs/cmdutils"
 	"github.com/loopholelabs/cmdutils/pkg/config"
 	"github.com/loopholelabs/cmdutils/pkg/printer"
+	"github.com/loopholelabs/cmdutils/pkg/schema"
 	"github.com/loopholelabs/cmdutils/pkg/version"
 )
 
@@ -45,27 +46,113 @@ type Command[T config.Config] struct {
 	newConfig     config.New[T]
 	config        T
 	setupCommands []SetupCommand[T]
+	middleware    []Middleware[T]
+	commandType   Type
 
-	format   printer.Format
-	debug    bool
-	logLevel types.Level
+	// viper holds the configuration of this command, so that several
+	// commands can coexist in one process.
+	viper *viper.Viper
+
+	// args are the arguments of the command. They default to os.Args[1:]
+	// but may be changed during tests.
+	args []string
+
+	// running is the command being executed.
+	running *cobra.Command
+
+	// initialized is set once the configuration, printer and logger of the
+	// current execution are set up.
+	initialized bool
+
+	cfgFile        string
+	logFile        string
+	defaultLogFile string
+
+	// logMu guards logClosers, which are also closed before the process is
+	// forced to exit by a signal.
+	logMu      sync.Mutex
+	logClosers []func() error
+
+	format     printer.Format
+	debug      bool
+	quiet      bool
+	assumeYes  bool
+	noColor    bool
+	accessible bool
+	theme      string
+	logLevel   types.Level
+
+	// logComponents and logStderrComponents override the log levels of
+	// components.
+	logComponents       map[string]types.Level
+	logFormat           logFormat
+	logStderrFormat     logFormat
+	logStderrLevel      types.Level
+	logStderrComponents map[string]types.Level
+	logSyslogAddress    string
+
+	// timeout bounds the execution of the command, and timeoutCtx is the
+	// context it applies to.
+	timeout       time.Duration
+	timeoutCtx    context.Context
+	cancelTimeout context.CancelFunc
+
+	// promptTimeout bounds how long prompts wait for an answer.
+	promptTimeout time.Duration
+
+	// handleSignals enables the signal handling set up by HandleSignals.
+	handleSignals bool
+	gracePeriod   time.Duration
+
+	// shutdownDeadline is the end of the grace period started by a signal,
+	// and signalPrinter prints the notices about signals once the printer
+	// is set up.
+	signalMu         sync.Mutex
+	shutdownDeadline time.Time
+	signalPrinter    printer.Interface
+
+	outputFilePath string
+	outputFile     *outputFile
+
+	// filledArgs holds the positional arguments including the ones the user
+	// was prompted for, once they were.
+	filledArgs []string
+
+	// testPrinter replaces the printer of the Helper, for tests.
+	testPrinter printer.Interface
 
 	// The following io.Writer values should be used when outputting text. They
 	// default to os.Stdout and os.Stderr but may be changed during tests.
 	stdout io.Writer
 	stderr io.Writer
+
+	// notifySignals and exit default to relaying the signals of the process
+	// and os.Exit, but may be changed during tests.
+	notifySignals func(ch chan<- os.Signal) (stop func())
+	exit          func(code int)
 }
 
-var (
-	cfgFile        string
-	logFile        string
-	logOutput      io.Writer
-	logClosersLock sync.Mutex
-	logClosers     []func() error
-	replacer       = strings.NewReplacer("-", "_", ".", "_")
-)
+var replacer = strings.NewReplacer("-", "_", ".", "_")
+
+// colorMu guards the global colour setting of fatih/color.
+var colorMu sync.Mutex
 
-func New[T config.Config](cli string, short string, long string, noargs bool, version *version.Version[T], newConfig config.New[T], setupCommands []SetupCommand[T]) *Command[T] {
+// disableColors disables colours for the deprecated helpers of the printer
+// package, such as printer.Bold, which can't follow the setting of a
+// printer. They are never enabled again, so that commands running in
+// parallel don't race.
+func disableColors() {
+	colorMu.Lock()
+	defer colorMu.Unlock()
+
+	if !color.NoColor {
+		color.NoColor = true
+	}
+}
+
+// New returns a new Command. The run function of every command is wrapped
+// with the given middleware, in order.
+func New[T config.Config](cli string, short string, long string, noargs bool, version *version.Version[T], newConfig config.New[T], setupCommands []SetupCommand[T], middleware ...Middleware[T]) *Command[T] {
 	c := &cobra.Command{
 		Use:              cli,
 		Short:            short,
@@ -81,13 +168,17 @@ func New[T config.Config](cli string, short string, long string, noargs bool, ve
 		version:       version,
 		newConfig:     newConfig,
 		setupCommands: setupCommands,
+		middleware:    middleware,
+		viper:         viper.New(),
 		stdout:        os.Stdout,
 		stderr:        os.Stderr,
+		notifySignals: notifySignals,
+		exit:          os.Exit,
 	}
 }
 
 func (c *Command[T]) Execute(ctx context.Context, commandType Type) int {
-	devEnv := fmt.Sprintf("%s_DISABLE_DEV_WARNING", strings.ToUpper(replacer.Replace(c.cli)))
+	devEnv := c.envName("disable-dev-warning")
 	devWarning := fmt.Sprintf("!! WARNING: You are using a self-compiled binary which is not officially supported.\n!! To dismiss this warning, set %s=true\n\n", devEnv)
 
 	if _, ok := os.LookupEnv(devEnv); !ok {
@@ -96,23 +187,35 @@ func (c *Command[T]) Execute(ctx context.Context, commandType Type) int {
 		}
 	}
 
+	ctx, stopSignals := c.watchSignals(ctx)
 	err := c.runCmd(ctx, commandType)
+	sig := stopSignals()
+	defer c.closeLogs()
+
+	if c.outputFile != nil {
+		if err == nil {
+			err = c.outputFile.Commit()
+		} else {
+			_ = c.outputFile.Abort()
+		}
+	}
 	if err == nil {
 		return 0
 	}
 
 	// print any user specific messages first
-	switch c.format {
-	case printer.JSON:
+	var panicErr *panicError
+	switch {
+	case errors.As(err, &panicErr):
+		c.printPanic(panicErr)
+	case c.format == printer.JSON:
 		_, _ = fmt.Fprintf(c.stderr, `{"error": "%s"}`, err)
 	default:
 		_, _ = fmt.Fprintf(c.stderr, "Error: %s\n", err)
 	}
 
-	logClosersLock.Lock()
-	defer logClosersLock.Unlock()
-	for _, closeLog := range logClosers {
-		_ = closeLog()
+	if errors.As(err, &panicErr) {
+		return cmdutils.PanicExitCode
 	}
 
 	// check if a sub command wants to return a specific exit code
@@ -121,13 +224,28 @@ func (c *Command[T]) Execute(ctx context.Context, commandType Type) int {
 		return cmdErr.ExitCode
 	}
 
+	if sig != nil && cmdutils.IsCanceled(err) {
+		return signalExitCode(sig)
+	}
+
+	if cmdutils.IsCanceled(err) {
+		return cmdutils.CanceledExitCode
+	}
+
 	return cmdutils.FatalErrExitCode
 }
 
 // runCmd adds all child commands to the root command, sets flags
 // appropriately, and runs the root command.
-func (c *Command[T]) runCmd(ctx context.Context, commandType Type) error {
+func (c *Command[T]) runCmd(ctx context.Context, commandType Type) (err error) {
+	defer c.recoverPanic(&err)
+
 	c.config = c.newConfig()
+	c.commandType = commandType
+	c.initialized = false
+	c.timeoutCtx = nil
+	c.running = nil
+	c.filledArgs = nil
 
 	configDir, err := c.config.DefaultConfigDir()
 	if err != nil {
@@ -145,76 +263,14 @@ func (c *Command[T]) runCmd(ctx context.Context, commandType Type) error {
 		logPath = path.Join(logDir, c.config.DefaultLogFile())
 	}
 
-	c.command.PersistentFlags().StringVar(&cfgFile, "config", "", fmt.Sprintf(`Config file (default "%s")`, configPath))
-	c.command.PersistentFlags().StringVar(&logFile, "log", logPath, "Log file")
+	c.command.PersistentFlags().StringVar(&c.cfgFile, "config", "", fmt.Sprintf(`Config file (default "%s")`, configPath))
+	c.command.PersistentFlags().StringVar(&c.logFile, "log", logPath, "Log file")
+	c.defaultLogFile = logPath
 
 	ch := &cmdutils.Helper[T]{
 		Config: c.config,
 	}
 
-	cobra.OnInitialize(func() {
-		err := c.initConfig()
-		if err != nil {
-			switch c.format {
-			case printer.JSON:
-				_, _ = fmt.Fprintf(c.stderr, `{"error": "%s"}`, err)
-			default:
-				_, _ = fmt.Fprintf(c.stderr, "Error: %s\n", err)
-			}
-
-			os.Exit(cmdutils.FatalErrExitCode)
-		}
-
-		ch.SetDebug(&c.debug)
-
-		ch.Printer = printer.NewPrinter(&c.format)
-
-		if strings.TrimSpace(logFile) == "" {
-			logOutput = c.stderr
-		} else {
-			if err := os.MkdirAll(filepath.Dir(logFile), 0700); err != nil {
-				switch c.format {
-				case printer.JSON:
-					_, _ = fmt.Fprintf(c.stderr, `{"error": "%s"}`, err)
-				default:
-					_, _ = fmt.Fprintf(c.stderr, "Error: %s\n", err)
-				}
-
-				os.Exit(cmdutils.FatalErrExitCode)
-			}
-
-			fileLogOutput, err := os.OpenFile(logFile, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0700)
-			if err != nil {
-				switch c.format {
-				case printer.JSON:
-					_, _ = fmt.Fprintf(c.stderr, `{"error": "%s"}`, err)
-				default:
-					_, _ = fmt.Fprintf(c.stderr, "Error: %s\n", err)
-				}
-
-				os.Exit(cmdutils.FatalErrExitCode)
-			}
-
-			logClosersLock.Lock()
-			defer logClosersLock.Unlock()
-			logClosers = append(logClosers, fileLogOutput.Close)
-
-			if ch.Debug() {
-				logOutput = io.MultiWriter(fileLogOutput, c.stderr)
-			} else {
-				logOutput = fileLogOutput
-			}
-		}
-
-		switch c.format {
-		case printer.JSON:
-			ch.Logger = logging.New(logging.Zerolog, strings.ToLower(c.cli), logOutput)
-		default:
-			ch.Logger = logging.New(logging.Slog, strings.ToLower(c.cli), logOutput)
-		}
-		ch.Logger.SetLevel(c.logLevel)
-	})
-
 	c.command.SilenceUsage = true
 	c.command.SilenceErrors = true
 
@@ -224,82 +280,342 @@ func (c *Command[T]) runCmd(ctx context.Context, commandType Type) error {
 	c.command.Flags().Bool("version", false, fmt.Sprintf("Show %s version", c.cli))
 
 	c.config.RootPersistentFlags(c.command.PersistentFlags())
+	if err = c.config.GlobalRequiredFlags(c.command); err != nil {
+		return err
+	}
 
-	c.command.PersistentFlags().VarP(printer.NewFormatValue(printer.Human, &c.format), "format", "f", "Show output in a specific format. Possible values: [human, json]")
-	if err = viper.BindPFlag("format", c.command.PersistentFlags().Lookup("format")); err != nil {
+	c.command.PersistentFlags().VarP(printer.NewFormatValue(printer.Human, &c.format), "format", "f", "Show output in a specific format. Possible values: [human, json, yaml, csv]")
+	if err = c.viper.BindPFlag("format", c.command.PersistentFlags().Lookup("format")); err != nil {
 		return err
 	}
 	_ = c.command.RegisterFlagCompletionFunc("format", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
-		return []string{"human", "json"}, cobra.ShellCompDirectiveDefault
+		return []string{"human", "json", "yaml", "csv"}, cobra.ShellCompDirectiveDefault
 	})
 
+	c.command.PersistentFlags().StringVarP(&c.outputFilePath, "output-file", "o", "", "Write output to a file, in the format matching its extension unless --format is set")
+	if err = c.viper.BindPFlag("output-file", c.command.PersistentFlags().Lookup("output-file")); err != nil {
+		return err
+	}
+
 	c.command.PersistentFlags().BoolVar(&c.debug, "debug", false, "Enable debug mode")
-	if err = viper.BindPFlag("debug", c.command.PersistentFlags().Lookup("debug")); err != nil {
+	if err = c.viper.BindPFlag("debug", c.command.PersistentFlags().Lookup("debug")); err != nil {
+		return err
+	}
+
+	c.command.PersistentFlags().BoolVarP(&c.quiet, "quiet", "q", false, "Only print resource identifiers, in the human format")
+	if err = c.viper.BindPFlag("quiet", c.command.PersistentFlags().Lookup("quiet")); err != nil {
+		return err
+	}
+
+	c.command.PersistentFlags().BoolVarP(&c.assumeYes, "yes", "y", false, "Automatically confirm prompts and accept their defaults")
+	if err = c.viper.BindPFlag("assume-yes", c.command.PersistentFlags().Lookup("yes")); err != nil {
 		return err
 	}
 
-	c.logLevel = types.InfoLevel
-	c.command.PersistentFlags().VarP(&c.logLevel, "log-level", "", "Specifies the level of log verbosity. Possible values: [fatal, error, warn, info, debug, trace]")
-	if err = viper.BindPFlag("log-level", c.command.PersistentFlags().Lookup("log-level")); err != nil {
+	// --force is kept as an alias of --yes.
+	c.command.PersistentFlags().BoolVar(&c.assumeYes, "force", false, "Alias of --yes")
+	_ = c.command.PersistentFlags().MarkHidden("force")
+
+	c.logLevel, c.logComponents = types.InfoLevel, nil
+	c.command.PersistentFlags().VarP(&logLevels{level: &c.logLevel, components: &c.logComponents}, "log-level", "", "Specifies the level of log verbosity, optionally followed by levels of components such as info,http=debug. Possible values: [fatal, error, warn, info, debug, trace]")
+	if err = c.viper.BindPFlag("log-level", c.command.PersistentFlags().Lookup("log-level")); err != nil {
 		return err
 	}
 	_ = c.command.RegisterFlagCompletionFunc("log-level", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 		return []string{"fatal", "error", "warn", "info", "debug", "trace"}, cobra.ShellCompDirectiveDefault
 	})
 
-	c.command.PersistentFlags().BoolVar(&color.NoColor, "no-color", false, "Disable color output")
-	if err = viper.BindPFlag("no-color", c.command.PersistentFlags().Lookup("no-color")); err != nil {
+	c.logFormat = ""
+	c.command.PersistentFlags().Var(&c.logFormat, "log-format", "Format of the logs written to the log file and syslog, text unless --format is json. Possible values: [text, json, console]")
+	if err = c.viper.BindPFlag("log-format", c.command.PersistentFlags().Lookup("log-format")); err != nil {
+		return err
+	}
+
+	c.logStderrFormat = ""
+	c.command.PersistentFlags().Var(&c.logStderrFormat, "log-stderr-format", "Format of the logs written to stderr, defaults to --log-format. Possible values: [text, json, console]")
+	if err = c.viper.BindPFlag("log-stderr-format", c.command.PersistentFlags().Lookup("log-stderr-format")); err != nil {
+		return err
+	}
+
+	for _, name := range []string{"log-format", "log-stderr-format"} {
+		_ = c.command.RegisterFlagCompletionFunc(name, func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return logFormats, cobra.ShellCompDirectiveDefault
+		})
+	}
+
+	c.logStderrLevel, c.logStderrComponents = types.InfoLevel, nil
+	c.command.PersistentFlags().Var(&logLevels{level: &c.logStderrLevel, components: &c.logStderrComponents}, "log-stderr-level", "Level of the logs written to stderr, like --log-level, which it defaults to. Possible values: [fatal, error, warn, info, debug, trace]")
+	if err = c.viper.BindPFlag("log-stderr-level", c.command.PersistentFlags().Lookup("log-stderr-level")); err != nil {
+		return err
+	}
+	_ = c.command.RegisterFlagCompletionFunc("log-stderr-level", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"fatal", "error", "warn", "info", "debug", "trace"}, cobra.ShellCompDirectiveDefault
+	})
+
+	c.command.PersistentFlags().StringSlice("log-output", nil, "Destinations of the logs, the log file unless there is none or --debug is set. Possible values: [file, stderr, syslog]")
+	if err = c.viper.BindPFlag("log-output", c.command.PersistentFlags().Lookup("log-output")); err != nil {
+		return err
+	}
+	_ = c.command.RegisterFlagCompletionFunc("log-output", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return logOutputs, cobra.ShellCompDirectiveDefault
+	})
+
+	c.command.PersistentFlags().StringVar(&c.logSyslogAddress, "log-syslog-address", DefaultSyslogAddress, "Unix domain socket of the syslog daemon")
+	if err = c.viper.BindPFlag("log-syslog-address", c.command.PersistentFlags().Lookup("log-syslog-address")); err != nil {
+		return err
+	}
+
+	c.command.PersistentFlags().BoolVar(&c.noColor, "no-color", false, "Disable color output")
+	if err = c.viper.BindPFlag("no-color", c.command.PersistentFlags().Lookup("no-color")); err != nil {
+		return err
+	}
+
+	c.command.PersistentFlags().BoolVar(&c.accessible, "accessible", false, "Print screen reader friendly output without animations or symbols")
+	if err = c.viper.BindPFlag("accessible", c.command.PersistentFlags().Lookup("accessible")); err != nil {
+		return err
+	}
+
+	c.command.PersistentFlags().StringVar(&c.theme, "theme", "", "Color theme. Possible values: [dark, light, mono] or a path to a YAML theme file")
+	if err = c.viper.BindPFlag("theme", c.command.PersistentFlags().Lookup("theme")); err != nil {
+		return err
+	}
+	_ = c.command.RegisterFlagCompletionFunc("theme", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return []string{"dark", "light", "mono"}, cobra.ShellCompDirectiveDefault
+	})
+
+	c.command.PersistentFlags().DurationVar(&c.timeout, "timeout", 0, "Maximum duration of the command, such as 30s or 5m. Zero means no limit")
+	if err = c.viper.BindPFlag("timeout", c.command.PersistentFlags().Lookup("timeout")); err != nil {
+		return err
+	}
+
+	c.command.PersistentFlags().DurationVar(&c.promptTimeout, "prompt-timeout", 0, "Maximum duration to wait for an answer to a prompt, such as 1m. Zero means no limit")
+	if err = c.viper.BindPFlag("prompt-timeout", c.command.PersistentFlags().Lookup("prompt-timeout")); err != nil {
 		return err
 	}
 
 	c.command.AddCommand(c.version.Cmd(ch, c.cli))
+	c.command.AddCommand(schema.Cmd(ch))
 
 	for _, setup := range c.setupCommands {
 		setup(c.command, ch)
 	}
 
+	// The run functions are wrapped from the inside out, so commands get
+	// their prompted arguments, then run the middleware. The configuration
+	// is validated by the persistent pre-run hooks.
+	c.applyMiddleware(c.command, ch)
+	c.fillMissing(c.command, ch)
+	c.validateBeforeHooks(c.command)
+
+	// Setup commands may have set their own hook on the root command.
+	preRunE, preRun := c.command.PersistentPreRunE, c.command.PersistentPreRun
+	c.command.PersistentPreRun = nil
+	c.command.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := c.initialize(cmd, ch); err != nil {
+			return err
+		}
+
+		if err := c.validate(cmd); err != nil {
+			return err
+		}
+
+		switch {
+		case preRunE != nil:
+			return preRunE(cmd, args)
+		case preRun != nil:
+			preRun(cmd, args)
+		}
+		return nil
+	}
+
+	if c.args != nil {
+		c.command.SetArgs(c.args)
+	}
+
+	err = c.execute(ctx)
+	if ch.Printer != nil {
+		ch.Printer.Flush()
+	}
+	err = c.timeoutError(err)
+
+	var panicErr *panicError
+	if errors.As(err, &panicErr) && ch.Logger != nil {
+		ch.Logger.Error().Str("panic", fmt.Sprint(panicErr.value)).Str("report", panicErr.report).Msg("command panicked")
+	}
+
+	if shutdownErr := c.shutdown(ctx, ch); shutdownErr != nil {
+		err = errors.Join(err, shutdownErr)
+	}
+
+	return err
+}
+
+// execute runs the root command. Panics are recovered, so that the shutdown
+// hooks still run.
+func (c *Command[T]) execute(ctx context.Context) (err error) {
+	defer c.recoverPanic(&err)
 	return c.command.ExecuteContext(ctx)
 }
 
+// initialize loads the configuration and sets up the printer and logger of
+// ch, once per execution of cmd. Arguments are validated before the
+// persistent pre-run hooks, so it is called by both.
+func (c *Command[T]) initialize(cmd *cobra.Command, ch *cmdutils.Helper[T]) error {
+	if c.initialized {
+		return nil
+	}
+	c.initialized = true
+	c.running = cmd
+
+	err := c.initConfig()
+	if err != nil {
+		return err
+	}
+
+	skipRequiredFlags(cmd)
+
+	c.setTimeout(cmd)
+
+	ch.SetDebug(&c.debug)
+
+	c.assumeYes = c.assumeYes || c.viper.GetBool("assume-yes")
+	ch.SetAssumeYes(&c.assumeYes)
+
+	p := printer.NewPrinter(&c.format)
+	if c.theme != "" {
+		theme, err := printer.LoadTheme(c.theme)
+		if err != nil {
+			return err
+		}
+		p.SetTheme(theme)
+	}
+	p.SetQuiet(c.quiet)
+	noColor := c.noColor || c.viper.GetBool("no-color")
+	p.SetNoColor(noColor)
+	if noColor {
+		disableColors()
+	}
+	p.SetAccessible(c.accessible || c.viper.GetBool("accessible"))
+	p.SetInteractive(c.commandType == Interactive)
+	p.SetAssumeYes(c.assumeYes)
+	p.SetAssumeYesHint("yes", c.envName("assume-yes"))
+	p.SetPromptTimeout(c.viper.GetDuration("prompt-timeout"))
+	p.SetContext(cmd.Context())
+
+	if c.outputFilePath != "" {
+		c.outputFile = newOutputFile(c.outputFilePath)
+
+		// Only resources are written to the output file, in the format
+		// matching its extension unless --format is set. Human-readable
+		// messages keep going to the terminal.
+		if format, ok := printer.FormatFromPath(c.outputFilePath); ok && !c.command.PersistentFlags().Changed("format") {
+			p.SetResourceFormat(format)
+		}
+		p.SetResourceOutput(c.outputFile)
+		if c.format == printer.Human {
+			p.SetHumanOutput(colorOutput(c.stdout))
+		}
+	} else {
+		p.SetResourceOutput(c.stdout)
+	}
+	p.SetEventOutput(c.stderr)
+
+	// Quiet mode prints identifiers instead of the human-readable resource,
+	// other formats always print whole resources.
+	if format := p.ResourceFormat(); c.quiet && format != printer.Human {
+		return fmt.Errorf("--quiet can't be used with the %s format", format.String())
+	}
+	ch.Printer = p
+	if c.testPrinter != nil {
+		ch.Printer = c.testPrinter
+	}
+
+	c.signalMu.Lock()
+	c.signalPrinter = ch.Printer
+	c.signalMu.Unlock()
+
+	if err := c.setupLogger(ch); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// colorOutput returns w, translating colors for Windows consoles if it is a
+// terminal.
+func colorOutput(w io.Writer) io.Writer {
+	if f, ok := w.(*os.File); ok {
+		return colorable.NewColorable(f)
+	}
+	return w
+}
+
+// closeLogs closes the log files opened by initialize.
+func (c *Command[T]) closeLogs() {
+	c.logMu.Lock()
+	defer c.logMu.Unlock()
+
+	for _, closeLog := range c.logClosers {
+		_ = closeLog()
+	}
+	c.logClosers = nil
+}
+
+// addLogCloser adds a function closing a log output to the ones called by
+// closeLogs.
+func (c *Command[T]) addLogCloser(closeLog func() error) {
+	c.logMu.Lock()
+	defer c.logMu.Unlock()
+	c.logClosers = append(c.logClosers, closeLog)
+}
+
+// envName returns the name of the environment variable for the given
+// configuration key.
+func (c *Command[T]) envName(key string) string {
+	return strings.ToUpper(replacer.Replace(fmt.Sprintf("%s_%s", c.cli, key)))
+}
+
 // initConfig reads in config file and ENV variables if set.
 func (c *Command[T]) initConfig() error {
-	if cfgFile != "" {
+	if c.cfgFile != "" {
 		// Use config file from the flag.
-		viper.SetConfigFile(cfgFile)
+		c.viper.SetConfigFile(c.cfgFile)
 	} else {
 		configDir, err := c.config.DefaultConfigDir()
 		if err != nil {
 			return fmt.Errorf("failed to read default configuration directory: %w", err)
 		}
 
-		viper.AddConfigPath(configDir)
+		c.viper.AddConfigPath(configDir)
 
 		configFile := c.config.DefaultConfigFile()
 		configFileSplit := strings.Split(configFile, ".")
-		viper.SetConfigName(configFileSplit[0])
+		c.viper.SetConfigName(configFileSplit[0])
 		if len(configFileSplit) > 1 {
-			viper.SetConfigType(configFileSplit[1])
+			c.viper.SetConfigType(configFileSplit[1])
 		}
 	}
 
-	viper.SetEnvPrefix(strings.ToUpper(c.cli))
-	viper.SetEnvKeyReplacer(replacer)
-	viper.AutomaticEnv() // read in environment variables that match
+	c.viper.SetEnvPrefix(strings.ToUpper(c.cli))
+	c.viper.SetEnvKeyReplacer(replacer)
+	c.viper.AutomaticEnv() // read in environment variables that match
 
 	// If a config file is found, read it in.
-	if err := viper.ReadInConfig(); err != nil {
+	if err := c.viper.ReadInConfig(); err != nil {
 		if _, ok := err.(viper.ConfigFileNotFoundError); !ok {
 			// Only handle errors when it's something unrelated to the config file not
 			// existing.
 			return fmt.Errorf("failed to read configuration: %w", err)
 		}
 	}
-	err := viper.Unmarshal(c.config, viper.DecodeHook(mapstructure.TextUnmarshallerHookFunc()))
+	err := c.viper.Unmarshal(c.config, viper.DecodeHook(mapstructure.TextUnmarshallerHookFunc()))
 	if err != nil {
 		return fmt.Errorf("failed to unmarshal configuration: %w", err)
 	}
 
-	c.postInitCommands(c.command.Commands())
+	if err := c.postInitCommands(c.command.Commands()); err != nil {
+		return err
+	}
 
 	if c.config.GetConfigFile() != "" {
 		err := os.MkdirAll(filepath.Dir(c.config.GetConfigFile()), 0700)
@@ -315,30 +631,43 @@ func (c *Command[T]) initConfig() error {
 
 // Hacky fix for getting Cobra required flags and Viper playing well together.
 // See: https://github.com/spf13/viper/issues/397
-func (c *Command[T]) postInitCommands(commands []*cobra.Command) {
+func (c *Command[T]) postInitCommands(commands []*cobra.Command) error {
 	for _, cmd := range commands {
-		c.presetRequiredFlags(cmd)
+		if err := c.presetRequiredFlags(cmd); err != nil {
+			return err
+		}
 		if cmd.HasSubCommands() {
-			c.postInitCommands(cmd.Commands())
+			if err := c.postInitCommands(cmd.Commands()); err != nil {
+				return err
+			}
 		}
 	}
+
+	return nil
 }
 
-func (c *Command[T]) presetRequiredFlags(cmd *cobra.Command) {
-	err := viper.BindPFlags(cmd.Flags())
+func (c *Command[T]) presetRequiredFlags(cmd *cobra.Command) error {
+	err := c.viper.BindPFlags(cmd.Flags())
 	if err != nil {
-		log.Fatalf("error binding flags: %v", err)
+		return fmt.Errorf("error binding flags: %w", err)
 	}
 
 	cmd.Flags().VisitAll(func(f *pflag.Flag) {
-		if viper.IsSet(f.Name) && viper.GetString(f.Name) != "" {
-			err = cmd.Flags().Set(f.Name, viper.GetString(f.Name))
-			if err != nil {
-				log.Fatalf("error setting flag %s: %v", f.Name, err)
+		if err != nil {
+			return
+		}
+		if c.viper.IsSet(f.Name) && c.viper.GetString(f.Name) != "" {
+			if setErr := cmd.Flags().Set(f.Name, c.viper.GetString(f.Name)); setErr != nil {
+				err = fmt.Errorf("error setting flag %s: %w", f.Name, setErr)
 			}
 		}
 	})
+	if err != nil {
+		return err
+	}
 
-	c.config.SetConfigFile(viper.ConfigFileUsed())
-	c.config.SetLogFile(logFile)
+	c.config.SetConfigFile(c.viper.ConfigFileUsed())
+	c.config.SetLogFile(c.logFile)
+
+	return nil
 }
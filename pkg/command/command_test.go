@@ -4,12 +4,27 @@ package command
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"runtime"
+	"slices"
+	"strings"
+	"syscall"
 	"testing"
+	"time"
 
+	"github.com/fatih/color"
 	"github.com/loopholelabs/cmdutils"
+	"github.com/loopholelabs/cmdutils/pkg/printer"
+	"github.com/loopholelabs/cmdutils/pkg/printer/printertest"
 	"github.com/loopholelabs/logging/loggers/zerolog"
 	"github.com/loopholelabs/logging/types"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/stretchr/testify/require"
 )
 
@@ -90,6 +105,8 @@ func TestLogLevel(t *testing.T) {
 
 	for _, tc := range testCases {
 		t.Run(fmt.Sprintf("%s/default", tc.name), func(t *testing.T) {
+			t.Parallel()
+
 			h := NewTestCommandHarness(t, fn)
 
 			rc := h.Execute(context.Background(), tc.args)
@@ -113,6 +130,8 @@ func TestLogLevel(t *testing.T) {
 		})
 
 		t.Run(fmt.Sprintf("%s/human", tc.name), func(t *testing.T) {
+			t.Parallel()
+
 			h := NewTestCommandHarness(t, fn)
 
 			args := make([]string, len(tc.args))
@@ -140,6 +159,8 @@ func TestLogLevel(t *testing.T) {
 		})
 
 		t.Run(fmt.Sprintf("%s/json", tc.name), func(t *testing.T) {
+			t.Parallel()
+
 			h := NewTestCommandHarness(t, fn)
 
 			args := make([]string, len(tc.args))
@@ -167,3 +188,1312 @@ func TestLogLevel(t *testing.T) {
 		})
 	}
 }
+
+func TestOutputFile(t *testing.T) {
+	t.Setenv("TEST_DISABLE_DEV_WARNING", "true")
+
+	type resource struct {
+		Name string `json:"name"`
+		Size int    `json:"size"`
+	}
+
+	testCases := []struct {
+		name     string
+		file     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "json extension",
+			file:     "out.json",
+			expected: "{\n  \"name\": \"a\",\n  \"size\": 1\n}\n",
+		},
+		{
+			name:     "yaml extension",
+			file:     "out.yaml",
+			expected: "name: a\nsize: 1\n",
+		},
+		{
+			name:     "csv extension",
+			file:     "out.csv",
+			expected: "name,size\na,1\n",
+		},
+		{
+			name:     "format overrides extension",
+			file:     "out.json",
+			args:     []string{"--format", "yaml"},
+			expected: "name: a\nsize: 1\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := NewTestCommandHarness(t, func(ch *cmdutils.Helper[*TestConfig]) error {
+				return ch.Printer.PrintResource(resource{Name: "a", Size: 1})
+			})
+
+			path := filepath.Join(t.TempDir(), tc.file)
+			args := append([]string{"run", "--output-file", path}, tc.args...)
+
+			rc := h.Execute(context.Background(), args)
+			require.Zero(t, rc, "expected no error, got:\n%s", h.Stderr())
+
+			b, err := os.ReadFile(path)
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, string(b))
+
+			entries, err := os.ReadDir(filepath.Dir(path))
+			require.NoError(t, err)
+			require.Len(t, entries, 1)
+		})
+	}
+
+	t.Run("failure leaves no file", func(t *testing.T) {
+		t.Parallel()
+
+		h := NewTestCommandHarness(t, func(ch *cmdutils.Helper[*TestConfig]) error {
+			require.Equal(t, printer.Human, ch.Printer.Format())
+			require.Equal(t, printer.JSON, ch.Printer.ResourceFormat())
+
+			ch.Printer.Println("Creating a")
+			_ = ch.Printer.PrintResource(resource{Name: "a", Size: 1})
+			return errors.New("failed")
+		})
+
+		path := filepath.Join(t.TempDir(), "out.json")
+
+		rc := h.Execute(context.Background(), []string{"run", "-o", path})
+		require.NotZero(t, rc)
+
+		// The inferred format only applies to the output file.
+		require.Equal(t, "Creating a\n", h.Stdout())
+		require.Equal(t, "Error: failed\n", h.Stderr())
+
+		entries, err := os.ReadDir(filepath.Dir(path))
+		require.NoError(t, err)
+		require.Empty(t, entries)
+	})
+
+	t.Run("success without output truncates", func(t *testing.T) {
+		t.Parallel()
+
+		h := NewTestCommandHarness(t, func(ch *cmdutils.Helper[*TestConfig]) error {
+			return nil
+		})
+
+		path := filepath.Join(t.TempDir(), "out.json")
+		require.NoError(t, os.WriteFile(path, []byte(`{"name":"old"}`), 0600))
+
+		rc := h.Execute(context.Background(), []string{"run", "-o", path})
+		require.Zero(t, rc, "expected no error, got:\n%s", h.Stderr())
+
+		b, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Empty(t, b)
+
+		info, err := os.Stat(path)
+		require.NoError(t, err)
+		require.Equal(t, os.FileMode(0600), info.Mode().Perm())
+	})
+}
+
+func TestQuiet(t *testing.T) {
+	t.Setenv("TEST_DISABLE_DEV_WARNING", "true")
+
+	type resource struct {
+		Name string `json:"name" table:",key"`
+	}
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+		err      string
+	}{
+		{
+			name:     "human",
+			expected: "a\n",
+		},
+		{
+			name: "format",
+			args: []string{"--format", "json"},
+			err:  "--quiet can't be used with the json format",
+		},
+		{
+			name: "inferred format",
+			args: []string{"-o", "out.yaml"},
+			err:  "--quiet can't be used with the yaml format",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := NewTestCommandHarness(t, func(ch *cmdutils.Helper[*TestConfig]) error {
+				ch.Printer.Println("Listing resources")
+				return ch.Printer.PrintResource([]resource{{Name: "a"}})
+			})
+
+			args := append([]string{"run", "-q"}, tc.args...)
+			for i, arg := range args {
+				if arg == "-o" {
+					args[i+1] = filepath.Join(t.TempDir(), args[i+1])
+				}
+			}
+
+			rc := h.Execute(context.Background(), args)
+			if tc.err != "" {
+				require.NotZero(t, rc)
+				require.Contains(t, h.Stderr(), tc.err)
+				return
+			}
+
+			require.Zero(t, rc, "expected no error, got:\n%s", h.Stderr())
+			require.Equal(t, tc.expected, h.Stdout())
+		})
+	}
+}
+
+func TestEventOutput(t *testing.T) {
+	t.Setenv("TEST_DISABLE_DEV_WARNING", "true")
+
+	h := NewTestCommandHarness(t, func(ch *cmdutils.Helper[*TestConfig]) error {
+		ch.Printer.Success("Created %s", "a")
+		return ch.Printer.PrintResource(map[string]string{"name": "a"})
+	})
+
+	rc := h.Execute(context.Background(), []string{"run", "--format", "json"})
+	require.Zero(t, rc, h.Stderr())
+	require.JSONEq(t, `{"name":"a"}`, h.Stdout())
+	require.JSONEq(t, `{"level":"success","message":"Created a"}`, h.Stderr())
+}
+
+func TestAssumeYes(t *testing.T) {
+	t.Setenv("TEST_DISABLE_DEV_WARNING", "true")
+
+	testCases := []struct {
+		name     string
+		args     []string
+		env      string
+		expected bool
+	}{
+		{
+			name:     "default",
+			args:     []string{"run"},
+			expected: false,
+		},
+		{
+			name:     "yes",
+			args:     []string{"run", "--yes"},
+			expected: true,
+		},
+		{
+			name:     "shorthand",
+			args:     []string{"run", "-y"},
+			expected: true,
+		},
+		{
+			name:     "force",
+			args:     []string{"run", "--force"},
+			expected: true,
+		},
+		{
+			name:     "env",
+			args:     []string{"run"},
+			env:      "true",
+			expected: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if tc.env != "" {
+				t.Setenv("TEST_ASSUME_YES", tc.env)
+			}
+
+			var assumeYes bool
+			h := NewTestCommandHarness(t, func(ch *cmdutils.Helper[*TestConfig]) error {
+				assumeYes = ch.AssumeYes()
+				return ch.Printer.ConfirmCommand("my-resource", "delete", "deletion")
+			})
+
+			rc := h.Execute(context.Background(), tc.args)
+			require.Equal(t, tc.expected, assumeYes)
+			if tc.expected {
+				require.Zero(t, rc, "expected no error, got:\n%s", h.Stderr())
+				return
+			}
+
+			require.NotZero(t, rc)
+			require.Contains(t, h.Stderr(), `cannot confirm deletion "my-resource" (run with --yes or set TEST_ASSUME_YES=true to override)`)
+		})
+	}
+}
+
+func TestCanceledExitCode(t *testing.T) {
+	t.Setenv("TEST_DISABLE_DEV_WARNING", "true")
+
+	testCases := []struct {
+		name string
+		err  error
+	}{
+		{
+			name: "interrupted prompt",
+			err:  printer.ErrPromptInterrupted,
+		},
+		{
+			name: "context canceled",
+			err:  fmt.Errorf("failed to wait: %w", context.Canceled),
+		},
+		{
+			name: "canceled",
+			err:  cmdutils.ErrCanceled,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := NewTestCommandHarness(t, func(ch *cmdutils.Helper[*TestConfig]) error {
+				return tc.err
+			})
+
+			rc := h.Execute(context.Background(), []string{"run"})
+			require.Equal(t, cmdutils.CanceledExitCode, rc)
+		})
+	}
+}
+
+func TestMissingArgsNoninteractive(t *testing.T) {
+	t.Setenv("TEST_DISABLE_DEV_WARNING", "true")
+
+	setupGet := func(root *cobra.Command, ch *cmdutils.Helper[*TestConfig]) {
+		cmd := &cobra.Command{
+			Use:  "get <name>",
+			Args: cmdutils.RequiredArgs("name"),
+			RunE: func(cmd *cobra.Command, args []string) error {
+				return nil
+			},
+		}
+		cmd.Flags().String("region", "", "Region of the resource")
+		_ = cmd.MarkFlagRequired("region")
+		root.AddCommand(cmd)
+	}
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "missing argument",
+			args:     []string{"get"},
+			expected: "missing argument <name>",
+		},
+		{
+			name:     "missing flag",
+			args:     []string{"get", "my-resource"},
+			expected: `required flag(s) "region" not set`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := NewTestCommandHarness(t, nil)
+			h.cmd.setupCommands = append(h.cmd.setupCommands, setupGet)
+
+			rc := h.Execute(context.Background(), tc.args)
+			require.NotZero(t, rc)
+			require.Contains(t, h.Stderr(), tc.expected)
+		})
+	}
+}
+
+func TestMissingArgsInteractive(t *testing.T) {
+	t.Setenv("TEST_DISABLE_DEV_WARNING", "true")
+
+	type result struct {
+		preRunArgs []string
+		runArgs    []string
+		region     string
+		token      string
+	}
+
+	setupGet := func(res *result) SetupCommand[*TestConfig] {
+		return func(root *cobra.Command, ch *cmdutils.Helper[*TestConfig]) {
+			cmd := &cobra.Command{
+				Use:  "get <name>",
+				Args: cmdutils.RequiredArgs("name"),
+				PreRunE: func(cmd *cobra.Command, args []string) error {
+					res.preRunArgs = args
+					return nil
+				},
+				RunE: func(cmd *cobra.Command, args []string) error {
+					res.runArgs = args
+					res.region, _ = cmd.Flags().GetString("region")
+					res.token, _ = cmd.Flags().GetString("token")
+					return nil
+				},
+			}
+			cmd.Flags().String("region", "", "Region of the resource")
+			_ = cmd.MarkFlagRequired("region")
+			_ = cmd.RegisterFlagCompletionFunc("region", func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective) {
+				return []string{"eu\tEurope", "us\tUnited States"}, cobra.ShellCompDirectiveNoFileComp
+			})
+			cmd.Flags().String("token", "", "API token")
+			_ = cmd.MarkFlagRequired("token")
+			_ = cmdutils.MarkFlagSecret(cmd.Flags(), "token")
+			root.AddCommand(cmd)
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		args     []string
+		answers  []interface{}
+		prompts  []printertest.Prompt
+		expected result
+	}{
+		{
+			name:    "all missing",
+			args:    []string{"get"},
+			answers: []interface{}{"my-resource", "eu", "secret"},
+			prompts: []printertest.Prompt{
+				{Method: "Input", Message: "name", Default: ""},
+				{Method: "Select", Flag: "region", Message: "Region of the resource", Options: []string{"eu", "us"}, Default: ""},
+				{Method: "Password", Flag: "token", Message: "API token"},
+			},
+			expected: result{
+				preRunArgs: []string{"my-resource"},
+				runArgs:    []string{"my-resource"},
+				region:     "eu",
+				token:      "secret",
+			},
+		},
+		{
+			name:    "secret flag missing",
+			args:    []string{"get", "my-resource", "--region", "us"},
+			answers: []interface{}{"secret"},
+			prompts: []printertest.Prompt{
+				{Method: "Password", Flag: "token", Message: "API token"},
+			},
+			expected: result{
+				preRunArgs: []string{"my-resource"},
+				runArgs:    []string{"my-resource"},
+				region:     "us",
+				token:      "secret",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var res result
+			rec := printertest.NewRecorder()
+			rec.Answer(tc.answers...)
+
+			h := NewTestCommandHarness(t, nil)
+			h.cmd.setupCommands = append(h.cmd.setupCommands, setupGet(&res))
+			h.SetPrinter(rec)
+
+			rc := h.Execute(context.Background(), tc.args)
+			require.Zero(t, rc, h.Stderr())
+			require.Equal(t, tc.prompts, rec.Prompts())
+			require.Equal(t, tc.expected, res)
+		})
+	}
+}
+
+func TestIndependentCommands(t *testing.T) {
+	t.Setenv("TEST_DISABLE_DEV_WARNING", "true")
+
+	withConfig := NewTestCommandHarness(t, nil)
+	require.NoError(t, os.WriteFile(withConfig.defaultConfigFile, []byte("debug: true\n"), 0600))
+
+	rc := withConfig.Execute(context.Background(), []string{"run", "--config", withConfig.defaultConfigFile, "--log-level", "debug"})
+	require.Zero(t, rc, "expected no error, got:\n%s", withConfig.Stderr())
+	require.True(t, withConfig.config.Debug)
+
+	// Neither the configuration nor the flags of the first command leak into
+	// the second one.
+	h := NewTestCommandHarness(t, nil)
+	rc = h.Execute(context.Background(), []string{"run"})
+	require.Zero(t, rc, "expected no error, got:\n%s", h.Stderr())
+	require.False(t, h.config.Debug)
+	require.Equal(t, types.InfoLevel, h.cmd.logLevel)
+}
+
+func TestParallelColors(t *testing.T) {
+	t.Setenv("TEST_DISABLE_DEV_WARNING", "true")
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("FORCE_COLOR", "1")
+
+	// Colours are a setting of each printer, so commands with different
+	// settings can run in parallel.
+	for _, noColor := range []bool{false, true} {
+		t.Run(fmt.Sprintf("no-color=%t", noColor), func(t *testing.T) {
+			t.Parallel()
+
+			var theme *printer.Theme
+			h := NewTestCommandHarness(t, func(ch *cmdutils.Helper[*TestConfig]) error {
+				theme = ch.Printer.Theme()
+				return nil
+			})
+
+			rc := h.Execute(context.Background(), []string{"run", fmt.Sprintf("--no-color=%t", noColor)})
+			require.Zero(t, rc, h.Stderr())
+			require.Equal(t, noColor, len(theme.Accent) == 0)
+			require.Equal(t, noColor, theme.Accent.Sprint("a") == "a")
+		})
+	}
+}
+
+func TestNoColor(t *testing.T) {
+	t.Setenv("TEST_DISABLE_DEV_WARNING", "true")
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("FORCE_COLOR", "1")
+
+	// The deprecated helpers follow the global setting, which is disabled
+	// for test binaries whose stdout isn't a terminal.
+	noColor := color.NoColor
+	color.NoColor = false
+	t.Cleanup(func() { color.NoColor = noColor })
+
+	var out []string
+	h := NewTestCommandHarness(t, func(ch *cmdutils.Helper[*TestConfig]) error {
+		out = []string{printer.Bold("a"), printer.BoldRed("b"), ch.Printer.Theme().Accent.Sprint("c")}
+		return nil
+	})
+
+	rc := h.Execute(context.Background(), []string{"run", "--no-color"})
+	require.Zero(t, rc, h.Stderr())
+	require.Equal(t, []string{"a", "b", "c"}, out)
+}
+
+func TestInitializationErrors(t *testing.T) {
+	t.Setenv("TEST_DISABLE_DEV_WARNING", "true")
+
+	notADir := filepath.Join(t.TempDir(), "file")
+	require.NoError(t, os.WriteFile(notADir, nil, 0600))
+
+	invalidConfig := filepath.Join(t.TempDir(), "config.yml")
+	require.NoError(t, os.WriteFile(invalidConfig, []byte("debug: [\n"), 0600))
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected string
+	}{
+		{
+			name:     "invalid config",
+			args:     []string{"run", "--config", invalidConfig},
+			expected: "Error: failed to read configuration",
+		},
+		{
+			name:     "unknown theme",
+			args:     []string{"run", "--theme", "solarized"},
+			expected: `Error: unknown theme "solarized"`,
+		},
+		{
+			name:     "log directory",
+			args:     []string{"run", "--log", filepath.Join(notADir, "test.log")},
+			expected: "Error: failed to create log directory",
+		},
+		{
+			name:     "json",
+			args:     []string{"run", "--theme", "solarized", "--format", "json"},
+			expected: `{"error": "unknown theme`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var ran bool
+			h := NewTestCommandHarness(t, func(ch *cmdutils.Helper[*TestConfig]) error {
+				ran = true
+				return nil
+			})
+
+			rc := h.Execute(context.Background(), tc.args)
+			require.Equal(t, cmdutils.FatalErrExitCode, rc)
+			require.Contains(t, h.Stderr(), tc.expected)
+			require.False(t, ran)
+		})
+	}
+}
+
+func TestValidation(t *testing.T) {
+	t.Setenv("TEST_DISABLE_DEV_WARNING", "true")
+
+	lowercase := func(c *TestConfig) error {
+		if c.Name != strings.ToLower(c.Name) {
+			return errors.New("name must be lowercase")
+		}
+		return nil
+	}
+
+	setupConfig := func(root *cobra.Command, ch *cmdutils.Helper[*TestConfig]) {
+		config := &cobra.Command{Use: "config"}
+		config.AddCommand(&cobra.Command{
+			Use:  "init",
+			RunE: func(cmd *cobra.Command, args []string) error { return nil },
+		})
+		cmdutils.SkipValidation(config)
+		root.AddCommand(config)
+	}
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected int
+		stderr   string
+	}{
+		{
+			name:     "valid",
+			args:     []string{"run", "--name", "a"},
+			expected: 0,
+		},
+		{
+			name:     "invalid",
+			args:     []string{"run", "--name", "A"},
+			expected: cmdutils.InvalidInputExitCode,
+			stderr:   "invalid configuration: name must be lowercase",
+		},
+		{
+			name:     "required flag",
+			args:     []string{"run"},
+			expected: cmdutils.FatalErrExitCode,
+			stderr:   `required flag(s) "name" not set`,
+		},
+		{
+			name:     "version",
+			args:     []string{"version"},
+			expected: 0,
+		},
+		{
+			name:     "opt out",
+			args:     []string{"config", "init", "--name", "A"},
+			expected: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var ran bool
+			h := NewTestCommandHarness(t, func(ch *cmdutils.Helper[*TestConfig]) error {
+				ran = true
+				return nil
+			})
+			h.cmd.setupCommands = append(h.cmd.setupCommands, setupConfig)
+
+			newConfig := h.cmd.newConfig
+			h.cmd.newConfig = func() *TestConfig {
+				c := newConfig()
+				c.requireName = true
+				c.validate = lowercase
+				return c
+			}
+
+			rc := h.Execute(context.Background(), tc.args)
+			require.Equal(t, tc.expected, rc, h.Stderr())
+			require.Contains(t, h.Stderr(), tc.stderr)
+			if tc.args[0] == "run" {
+				require.Equal(t, tc.expected == 0, ran)
+			}
+		})
+	}
+}
+
+func TestValidationBeforeHooks(t *testing.T) {
+	t.Setenv("TEST_DISABLE_DEV_WARNING", "true")
+
+	testCases := []struct {
+		name       string
+		persistent bool
+	}{
+		{name: "pre-run"},
+		{name: "persistent pre-run", persistent: true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var hooks []string
+			hook := func(name string) func(*cobra.Command, []string) error {
+				return func(*cobra.Command, []string) error {
+					hooks = append(hooks, name)
+					return nil
+				}
+			}
+
+			h := NewTestCommandHarness(t, nil)
+			h.cmd.setupCommands = append(h.cmd.setupCommands, func(root *cobra.Command, ch *cmdutils.Helper[*TestConfig]) {
+				cmd := &cobra.Command{
+					Use:     "hooks",
+					PreRunE: hook("pre-run"),
+					RunE:    hook("run"),
+				}
+				if tc.persistent {
+					cmd.PersistentPreRunE = hook("persistent pre-run")
+				}
+				root.AddCommand(cmd)
+			})
+			h.cmd.middleware = append(h.cmd.middleware, func(next RunFunc[*TestConfig]) RunFunc[*TestConfig] {
+				return func(ch *cmdutils.Helper[*TestConfig], cmd *cobra.Command, args []string) error {
+					hooks = append(hooks, "middleware")
+					return next(ch, cmd, args)
+				}
+			})
+
+			newConfig := h.cmd.newConfig
+			h.cmd.newConfig = func() *TestConfig {
+				c := newConfig()
+				c.validate = func(*TestConfig) error { return errors.New("invalid") }
+				return c
+			}
+
+			rc := h.Execute(context.Background(), []string{"hooks"})
+			require.Equal(t, cmdutils.InvalidInputExitCode, rc, h.Stderr())
+			require.Contains(t, h.Stderr(), "invalid configuration: invalid")
+			require.Empty(t, hooks)
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	t.Setenv("TEST_DISABLE_DEV_WARNING", "true")
+
+	var calls []string
+	record := func(name string) Middleware[*TestConfig] {
+		return func(next RunFunc[*TestConfig]) RunFunc[*TestConfig] {
+			return func(ch *cmdutils.Helper[*TestConfig], cmd *cobra.Command, args []string) error {
+				require.NotNil(t, ch.Printer)
+				calls = append(calls, fmt.Sprintf("%s %s %v", name, cmd.Name(), args))
+				err := next(ch, cmd, args)
+				calls = append(calls, name+" done")
+				return err
+			}
+		}
+	}
+
+	h := NewTestCommandHarness(t, func(ch *cmdutils.Helper[*TestConfig]) error {
+		calls = append(calls, "run")
+		return nil
+	})
+	h.cmd.middleware = []Middleware[*TestConfig]{record("outer"), record("inner")}
+
+	rc := h.Execute(context.Background(), []string{"run", "a"})
+	require.Zero(t, rc, "expected no error, got:\n%s", h.Stderr())
+	require.Equal(t, []string{"outer run [a]", "inner run [a]", "run", "inner done", "outer done"}, calls)
+
+	t.Run("short circuit", func(t *testing.T) {
+		var ran bool
+		h := NewTestCommandHarness(t, func(ch *cmdutils.Helper[*TestConfig]) error {
+			ran = true
+			return nil
+		})
+		h.cmd.middleware = []Middleware[*TestConfig]{
+			func(next RunFunc[*TestConfig]) RunFunc[*TestConfig] {
+				return func(ch *cmdutils.Helper[*TestConfig], cmd *cobra.Command, args []string) error {
+					return &cmdutils.Error{Msg: "not logged in", ExitCode: cmdutils.ActionRequestedExitCode}
+				}
+			},
+		}
+
+		rc := h.Execute(context.Background(), []string{"run"})
+		require.Equal(t, cmdutils.ActionRequestedExitCode, rc)
+		require.Contains(t, h.Stderr(), "not logged in")
+		require.False(t, ran)
+	})
+}
+
+func TestSignals(t *testing.T) {
+	t.Setenv("TEST_DISABLE_DEV_WARNING", "true")
+
+	// run executes a command with the given arguments that waits for its
+	// context to be canceled, sends it the given signals and returns its exit
+	// code and the exit code it was forced to exit with, if any.
+	run := func(t *testing.T, args []string, gracePeriod time.Duration, sent ...os.Signal) (*TestCommandHarness, []string, int, int) {
+		var (
+			calls  []string
+			sentAt time.Time
+		)
+		forced := make(chan int, 1)
+		exited := make(chan struct{})
+		notified := make(chan chan<- os.Signal, 1)
+
+		h := NewTestCommandHarness(t, nil)
+		h.cmd.HandleSignals(gracePeriod)
+		h.cmd.notifySignals = func(ch chan<- os.Signal) func() {
+			notified <- ch
+			return func() {}
+		}
+		h.cmd.exit = func(code int) {
+			h.cmd.logMu.Lock()
+			if h.cmd.logClosers != nil {
+				t.Error("logs weren't closed before exiting")
+			}
+			h.cmd.logMu.Unlock()
+
+			forced <- code
+			close(exited)
+		}
+		h.cmd.middleware = []Middleware[*TestConfig]{
+			func(next RunFunc[*TestConfig]) RunFunc[*TestConfig] {
+				return func(ch *cmdutils.Helper[*TestConfig], cmd *cobra.Command, args []string) error {
+					ch.OnShutdown(func(ctx context.Context) error {
+						calls = append(calls, "first")
+						return nil
+					})
+					ch.OnShutdown(func(ctx context.Context) error {
+						// The grace period starts with the signal.
+						deadline, ok := ctx.Deadline()
+						calls = append(calls, fmt.Sprintf("second %t", ok && deadline.Before(sentAt.Add(gracePeriod+100*time.Millisecond))))
+						return errors.New("flush failed")
+					})
+
+					<-cmd.Context().Done()
+					time.Sleep(200 * time.Millisecond)
+					if len(sent) > 1 || gracePeriod < time.Second {
+						// Wait to be forced to exit.
+						<-exited
+					}
+					return cmd.Context().Err()
+				}
+			},
+		}
+
+		rc := make(chan int, 1)
+		go func() { rc <- h.Execute(context.Background(), append([]string{"run"}, args...)) }()
+
+		signals := <-notified
+		sentAt = time.Now()
+		for _, sig := range sent {
+			signals <- sig
+		}
+
+		code := <-rc
+		select {
+		case f := <-forced:
+			return h, calls, code, f
+		default:
+			return h, calls, code, 0
+		}
+	}
+
+	t.Run("graceful", func(t *testing.T) {
+		t.Parallel()
+
+		h, calls, rc, forced := run(t, nil, time.Minute, syscall.SIGTERM)
+		require.Equal(t, cmdutils.TerminatedExitCode, rc)
+		require.Zero(t, forced)
+		require.Equal(t, []string{"second true", "first"}, calls)
+		require.Contains(t, h.Stderr(), "Received terminated, shutting down")
+		require.Contains(t, h.Stderr(), "shutdown: flush failed")
+	})
+
+	t.Run("second signal", func(t *testing.T) {
+		t.Parallel()
+
+		log := filepath.Join(t.TempDir(), "test.log")
+		h, _, _, forced := run(t, []string{"--log", log}, time.Minute, os.Interrupt, os.Interrupt)
+		require.Equal(t, cmdutils.CanceledExitCode, forced)
+		require.Contains(t, h.Stderr(), "Received interrupt again, exiting")
+	})
+
+	t.Run("json", func(t *testing.T) {
+		t.Parallel()
+
+		h, _, _, _ := run(t, []string{"--format", "json"}, time.Minute, syscall.SIGTERM)
+		require.Contains(t, strings.Split(h.Stderr(), "\n"), `{"level":"warning","message":"Received terminated, shutting down. Send it again to exit immediately."}`)
+	})
+
+	t.Run("grace period", func(t *testing.T) {
+		t.Parallel()
+
+		h, _, _, forced := run(t, nil, 10*time.Millisecond, syscall.SIGTERM)
+		require.Equal(t, cmdutils.TerminatedExitCode, forced)
+		require.Contains(t, h.Stderr(), "Did not shut down within 10ms, exiting")
+	})
+
+	t.Run("hooks without signals", func(t *testing.T) {
+		t.Parallel()
+
+		var calls []string
+		h := NewTestCommandHarness(t, func(ch *cmdutils.Helper[*TestConfig]) error {
+			ch.OnShutdown(func(ctx context.Context) error {
+				calls = append(calls, "first")
+				return nil
+			})
+			ch.OnShutdown(func(ctx context.Context) error {
+				calls = append(calls, "second")
+				return nil
+			})
+			return nil
+		})
+
+		rc := h.Execute(context.Background(), []string{"run"})
+		require.Zero(t, rc, "expected no error, got:\n%s", h.Stderr())
+		require.Equal(t, []string{"second", "first"}, calls)
+	})
+}
+
+func TestTimeout(t *testing.T) {
+	t.Setenv("TEST_DISABLE_DEV_WARNING", "true")
+
+	// wait is a middleware that waits for the context of the command to
+	// expire, instead of running it.
+	wait := func(next RunFunc[*TestConfig]) RunFunc[*TestConfig] {
+		return func(ch *cmdutils.Helper[*TestConfig], cmd *cobra.Command, args []string) error {
+			<-cmd.Context().Done()
+			return fmt.Errorf("waiting: %w", cmd.Context().Err())
+		}
+	}
+
+	t.Run("flag", func(t *testing.T) {
+		t.Parallel()
+
+		h := NewTestCommandHarness(t, nil)
+		h.cmd.middleware = []Middleware[*TestConfig]{wait}
+
+		rc := h.Execute(context.Background(), []string{"run", "--timeout", "10ms"})
+		require.Equal(t, cmdutils.TimeoutExitCode, rc)
+		require.Equal(t, "Error: timed out after 10ms\n", h.Stderr())
+	})
+
+	t.Run("within timeout", func(t *testing.T) {
+		t.Parallel()
+
+		h := NewTestCommandHarness(t, nil)
+		rc := h.Execute(context.Background(), []string{"run", "--timeout", "1m"})
+		require.Zero(t, rc, "expected no error, got:\n%s", h.Stderr())
+	})
+
+	t.Run("other deadline", func(t *testing.T) {
+		t.Parallel()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		h := NewTestCommandHarness(t, nil)
+		h.cmd.middleware = []Middleware[*TestConfig]{wait}
+
+		rc := h.Execute(ctx, []string{"run"})
+		require.Equal(t, cmdutils.FatalErrExitCode, rc)
+		require.Contains(t, h.Stderr(), "waiting: context deadline exceeded")
+	})
+}
+
+func TestTimeoutEnv(t *testing.T) {
+	t.Setenv("TEST_DISABLE_DEV_WARNING", "true")
+	t.Setenv("TEST_TIMEOUT", "10ms")
+
+	h := NewTestCommandHarness(t, nil)
+	h.cmd.middleware = []Middleware[*TestConfig]{
+		func(next RunFunc[*TestConfig]) RunFunc[*TestConfig] {
+			return func(ch *cmdutils.Helper[*TestConfig], cmd *cobra.Command, args []string) error {
+				<-cmd.Context().Done()
+				return cmd.Context().Err()
+			}
+		},
+	}
+
+	rc := h.Execute(context.Background(), []string{"run"})
+	require.Equal(t, cmdutils.TimeoutExitCode, rc)
+	require.Contains(t, h.Stderr(), "timed out after 10ms")
+}
+
+func TestPanic(t *testing.T) {
+	t.Setenv("TEST_DISABLE_DEV_WARNING", "true")
+
+	testcases := []struct {
+		name  string
+		args  []string
+		stack bool
+		json  bool
+	}{
+		{
+			name: "human",
+			args: []string{"run", "--name", "web"},
+		},
+		{
+			name:  "debug",
+			args:  []string{"run", "--name", "web", "--debug"},
+			stack: true,
+		},
+		{
+			name: "json",
+			args: []string{"run", "--name", "web", "--format", "json"},
+			json: true,
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			var shutdown bool
+			h := NewTestCommandHarness(t, func(ch *cmdutils.Helper[*TestConfig]) error {
+				ch.OnShutdown(func(ctx context.Context) error {
+					shutdown = true
+					return nil
+				})
+				panic("boom")
+			})
+
+			rc := h.Execute(context.Background(), tc.args)
+			require.Equal(t, cmdutils.PanicExitCode, rc)
+			require.True(t, shutdown)
+
+			stderr := h.Stderr()
+			reports, err := filepath.Glob(filepath.Join(filepath.Dir(h.defaultLogFile), "crash-*.log"))
+			require.NoError(t, err)
+			require.Len(t, reports, 1, stderr)
+
+			if tc.json {
+				expected, err := json.Marshal(map[string]string{
+					"error":   "panic: boom",
+					"message": "This is a bug in test, please report it.",
+					"report":  reports[0],
+				})
+				require.NoError(t, err)
+
+				// The error envelope follows the log of the panic.
+				lines := strings.Split(strings.TrimSpace(stderr), "\n")
+				require.JSONEq(t, string(expected), lines[len(lines)-1])
+			} else {
+				require.Contains(t, stderr, "Error: panic: boom\n\nThis is a bug in test, please report it.\n")
+				require.Equal(t, tc.stack, strings.Contains(stderr, "goroutine "))
+				require.Contains(t, stderr, "A crash report was written to "+reports[0])
+			}
+
+			report, err := os.ReadFile(reports[0])
+			require.NoError(t, err)
+			require.Contains(t, string(report), "Command:    test run\n")
+			require.Contains(t, string(report), "Arguments:  "+strings.Join(tc.args, " ")+"\n")
+			require.Contains(t, string(report), "panic: boom\n\ngoroutine ")
+		})
+	}
+}
+
+func TestPanicRedactsSecretFlags(t *testing.T) {
+	t.Setenv("TEST_DISABLE_DEV_WARNING", "true")
+
+	h := NewTestCommandHarness(t, nil)
+	h.cmd.setupCommands = append(h.cmd.setupCommands,
+		func(root *cobra.Command, ch *cmdutils.Helper[*TestConfig]) {
+			root.PersistentFlags().String("api-key", "", "API key")
+			_ = cmdutils.MarkFlagSecret(root.PersistentFlags(), "api-key")
+
+			deploy := &cobra.Command{Use: "deploy", RunE: func(*cobra.Command, []string) error { return nil }}
+			deploy.Flags().StringP("token", "t", "", "Deploy token")
+			_ = cmdutils.MarkFlagSecret(deploy.Flags(), "token")
+			root.AddCommand(deploy)
+		},
+		// Panic before the command runs.
+		func(*cobra.Command, *cmdutils.Helper[*TestConfig]) { panic("boom") },
+	)
+
+	rc := h.Execute(context.Background(), []string{"deploy", "-tsecret", "--api-key", "key"})
+	require.Equal(t, cmdutils.PanicExitCode, rc)
+
+	reports, err := filepath.Glob(filepath.Join(filepath.Dir(h.defaultLogFile), "crash-*.log"))
+	require.NoError(t, err)
+	require.Len(t, reports, 1, h.Stderr())
+
+	report, err := os.ReadFile(reports[0])
+	require.NoError(t, err)
+	require.Contains(t, string(report), "Command:    test deploy\n")
+	require.Contains(t, string(report), "Arguments:  deploy -tREDACTED --api-key REDACTED\n")
+}
+
+func TestRedactArgs(t *testing.T) {
+	flags := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	flags.StringP("token", "t", "", "")
+	flags.StringP("name", "n", "", "")
+	flags.BoolP("verbose", "v", false, "")
+	require.NoError(t, cmdutils.MarkFlagSecret(flags, "token"))
+
+	testCases := []struct {
+		name     string
+		args     []string
+		expected []string
+	}{
+		{
+			name:     "long",
+			args:     []string{"deploy", "--token", "a", "--token=b", "--name", "web"},
+			expected: []string{"deploy", "--token", "REDACTED", "--token=REDACTED", "--name", "web"},
+		},
+		{
+			name:     "shorthand",
+			args:     []string{"-t", "a", "-t=b", "-tc"},
+			expected: []string{"-t", "REDACTED", "-t=REDACTED", "-tREDACTED"},
+		},
+		{
+			name:     "shorthand cluster",
+			args:     []string{"-vt", "a", "-vtb", "-vt=c", "-vn", "web"},
+			expected: []string{"-vt", "REDACTED", "-vtREDACTED", "-vt=REDACTED", "-vn", "web"},
+		},
+		{
+			name:     "flag values",
+			args:     []string{"--name", "-t", "-n", "--token", "-tv"},
+			expected: []string{"--name", "-t", "-n", "--token", "-tREDACTED"},
+		},
+		{
+			name:     "terminator",
+			args:     []string{"--", "--token", "a", "-ta"},
+			expected: []string{"--", "--token", "a", "-ta"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			args := slices.Clone(tc.args)
+			require.Equal(t, tc.expected, redactArgs(flags, args))
+			require.Equal(t, tc.args, args)
+		})
+	}
+}
+
+func TestLogDestinations(t *testing.T) {
+	t.Setenv("TEST_DISABLE_DEV_WARNING", "true")
+
+	fn := func(ch *cmdutils.Helper[*TestConfig]) error {
+		ch.Logger.Debug().Msg("DEBUG")
+		ch.Logger.Warn().Msg("WARN")
+		return nil
+	}
+
+	t.Run("file and stderr", func(t *testing.T) {
+		t.Parallel()
+
+		logFile := filepath.Join(t.TempDir(), "test.log")
+		h := NewTestCommandHarness(t, fn)
+		rc := h.Execute(context.Background(), []string{
+			"run", "--log", logFile, "--log-output", "file,stderr",
+			"--log-format", "json", "--log-level", "debug",
+			"--log-stderr-format", "console", "--log-stderr-level", "warn",
+		})
+		require.Zero(t, rc, "expected no error, got:\n%s", h.Stderr())
+
+		b, err := os.ReadFile(logFile)
+		require.NoError(t, err)
+		require.Contains(t, string(b), `"message":"DEBUG"`)
+		require.Contains(t, string(b), `"message":"WARN"`)
+
+		require.Contains(t, h.Stderr(), "WRN WARN")
+		require.NotContains(t, h.Stderr(), "DEBUG")
+	})
+
+	t.Run("set level keeps stderr level", func(t *testing.T) {
+		t.Parallel()
+
+		logFile := filepath.Join(t.TempDir(), "test.log")
+		h := NewTestCommandHarness(t, func(ch *cmdutils.Helper[*TestConfig]) error {
+			ch.Logger.SetLevel(types.DebugLevel)
+			return fn(ch)
+		})
+		rc := h.Execute(context.Background(), []string{
+			"run", "--log", logFile, "--log-output", "file,stderr",
+			"--log-level", "error", "--log-stderr-level", "warn",
+		})
+		require.Zero(t, rc, "expected no error, got:\n%s", h.Stderr())
+
+		b, err := os.ReadFile(logFile)
+		require.NoError(t, err)
+		require.Contains(t, string(b), "msg=DEBUG")
+
+		require.Contains(t, h.Stderr(), "msg=WARN")
+		require.NotContains(t, h.Stderr(), "DEBUG")
+	})
+
+	t.Run("debug mirrors file", func(t *testing.T) {
+		t.Parallel()
+
+		logFile := filepath.Join(t.TempDir(), "test.log")
+		h := NewTestCommandHarness(t, fn)
+		rc := h.Execute(context.Background(), []string{"run", "--log", logFile, "--debug"})
+		require.Zero(t, rc, "expected no error, got:\n%s", h.Stderr())
+
+		b, err := os.ReadFile(logFile)
+		require.NoError(t, err)
+		require.Contains(t, string(b), "msg=WARN")
+		require.Contains(t, h.Stderr(), "msg=WARN")
+	})
+
+	t.Run("syslog", func(t *testing.T) {
+		t.Parallel()
+
+		if runtime.GOOS == "windows" || runtime.GOOS == "plan9" {
+			t.Skip("syslog is not supported")
+		}
+
+		// Unix domain socket paths are limited to about 100 characters.
+		dir, err := os.MkdirTemp("", "syslog")
+		require.NoError(t, err)
+		t.Cleanup(func() { _ = os.RemoveAll(dir) })
+
+		address := filepath.Join(dir, "log")
+		conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: address, Net: "unixgram"})
+		require.NoError(t, err)
+		defer conn.Close()
+
+		h := NewTestCommandHarness(t, fn)
+		rc := h.Execute(context.Background(), []string{"run", "--log-output", "syslog", "--log-syslog-address", address})
+		require.Zero(t, rc, "expected no error, got:\n%s", h.Stderr())
+		require.NotContains(t, h.Stderr(), "WARN")
+
+		require.NoError(t, conn.SetReadDeadline(time.Now().Add(time.Second)))
+		b := make([]byte, 1024)
+		n, err := conn.Read(b)
+		require.NoError(t, err)
+		require.Contains(t, string(b[:n]), "test[")
+		require.Contains(t, string(b[:n]), "msg=WARN")
+	})
+
+	testcases := []struct {
+		name string
+		args []string
+		err  string
+	}{
+		{
+			name: "invalid output",
+			args: []string{"run", "--log-output", "stdout"},
+			err:  `failed to parse log output: "stdout"`,
+		},
+		{
+			name: "invalid format",
+			args: []string{"run", "--log-format", "xml"},
+			err:  `failed to parse log format: "xml"`,
+		},
+		{
+			name: "file without log file",
+			args: []string{"run", "--log-output", "file"},
+			err:  "logging to a file requires a log file",
+		},
+	}
+
+	for _, tc := range testcases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			t.Parallel()
+
+			h := NewTestCommandHarness(t, fn)
+			rc := h.Execute(context.Background(), tc.args)
+			require.NotZero(t, rc)
+			require.Contains(t, h.Stderr(), tc.err)
+		})
+	}
+}
+
+func TestLogDestinationsEnv(t *testing.T) {
+	t.Setenv("TEST_DISABLE_DEV_WARNING", "true")
+	t.Setenv("TEST_LOG_FORMAT", "json")
+	t.Setenv("TEST_LOG_STDERR_LEVEL", "debug")
+
+	h := NewTestCommandHarness(t, func(ch *cmdutils.Helper[*TestConfig]) error {
+		ch.Logger.Debug().Msg("DEBUG")
+		return nil
+	})
+
+	rc := h.Execute(context.Background(), []string{"run"})
+	require.Zero(t, rc, "expected no error, got:\n%s", h.Stderr())
+	require.Contains(t, h.Stderr(), `"message":"DEBUG"`)
+}
+
+func TestComponentLogLevels(t *testing.T) {
+	t.Setenv("TEST_DISABLE_DEV_WARNING", "true")
+
+	fn := func(ch *cmdutils.Helper[*TestConfig]) error {
+		ch.Logger.Info().Msg("root-info")
+
+		http := ch.Logger.SubLogger("http")
+		http.Debug().Msg("http-debug")
+		http.Trace().Msg("http-trace")
+		http.SubLogger("server").Debug().Msg("server-debug")
+		http.SubLogger("client").Trace().Msg("client-trace")
+
+		// Loggers with fields keep the levels of components.
+		http.With().Str("k", "v").Logger().Debug().Msg("with-debug")
+		http.With().Logger().SubLogger("client").Trace().Msg("with-client-trace")
+		ch.Logger.With().Logger().SubLogger("http").Debug().Msg("root-with-debug")
+
+		db := ch.Logger.SubLogger("db")
+		db.Info().Msg("db-info")
+		db.Warn().Msg("db-warn")
+		return nil
+	}
+
+	t.Run("flag", func(t *testing.T) {
+		t.Parallel()
+
+		h := NewTestCommandHarness(t, fn)
+		rc := h.Execute(context.Background(), []string{"run", "--log-level", "warn, http=debug,http:client=trace"})
+		require.Zero(t, rc, "expected no error, got:\n%s", h.Stderr())
+
+		for _, msg := range []string{"http-debug", "server-debug", "client-trace", "db-warn", "with-debug", "with-client-trace", "root-with-debug"} {
+			require.Contains(t, h.Stderr(), "msg="+msg)
+		}
+		for _, msg := range []string{"root-info", "http-trace", "db-info"} {
+			require.NotContains(t, h.Stderr(), "msg="+msg)
+		}
+		require.Contains(t, h.Stderr(), "source=test:http:client")
+	})
+
+	t.Run("stderr", func(t *testing.T) {
+		t.Parallel()
+
+		logFile := filepath.Join(t.TempDir(), "test.log")
+		h := NewTestCommandHarness(t, fn)
+		rc := h.Execute(context.Background(), []string{
+			"run", "--log", logFile, "--log-output", "file,stderr",
+			"--log-level", "error", "--log-stderr-level", "error,db=info",
+		})
+		require.Zero(t, rc, "expected no error, got:\n%s", h.Stderr())
+		require.Contains(t, h.Stderr(), "msg=db-info")
+
+		b, err := os.ReadFile(logFile)
+		require.NoError(t, err)
+		require.NotContains(t, string(b), "db-info")
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		t.Parallel()
+
+		h := NewTestCommandHarness(t, fn)
+		rc := h.Execute(context.Background(), []string{"run", "--log-level", "info,http=loud"})
+		require.NotZero(t, rc)
+		require.Contains(t, h.Stderr(), `invalid log level for component "http"`)
+	})
+}
+
+func TestComponentLogLevelsEnv(t *testing.T) {
+	t.Setenv("TEST_DISABLE_DEV_WARNING", "true")
+	t.Setenv("TEST_LOG_LEVEL", "info,db=trace")
+
+	h := NewTestCommandHarness(t, func(ch *cmdutils.Helper[*TestConfig]) error {
+		ch.Logger.Debug().Msg("root-debug")
+		ch.Logger.SubLogger("db").Trace().Msg("db-trace")
+		return nil
+	})
+
+	rc := h.Execute(context.Background(), []string{"run"})
+	require.Zero(t, rc, "expected no error, got:\n%s", h.Stderr())
+	require.Contains(t, h.Stderr(), "msg=db-trace")
+	require.NotContains(t, h.Stderr(), "root-debug")
+	require.Equal(t, "info,db=trace", h.cmd.command.PersistentFlags().Lookup("log-level").Value.String())
+}
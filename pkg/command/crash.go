@@ -0,0 +1,226 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/spf13/pflag"
+
+	"github.com/loopholelabs/cmdutils"
+	"github.com/loopholelabs/cmdutils/pkg/printer"
+)
+
+// redacted replaces the values of secret flags in crash reports.
+const redacted = "REDACTED"
+
+// panicError is returned by an execution that panicked.
+type panicError struct {
+	value interface{}
+	stack []byte
+
+	// report is the path of the crash report, if it could be written.
+	report    string
+	reportErr error
+}
+
+func (e *panicError) Error() string {
+	return fmt.Sprintf("panic: %v", e.value)
+}
+
+// recoverPanic recovers a panic of the current goroutine and replaces *err
+// with a panicError. It must be deferred.
+func (c *Command[T]) recoverPanic(err *error) {
+	r := recover()
+	if r == nil {
+		return
+	}
+
+	e := &panicError{value: r, stack: debug.Stack()}
+	e.report, e.reportErr = c.writeCrashReport(e)
+	*err = e
+}
+
+// writeCrashReport writes a report of e into the default log directory, and
+// returns its path.
+func (c *Command[T]) writeCrashReport(e *panicError) (string, error) {
+	dir, err := c.config.DefaultLogDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to get default log directory: %w", err)
+	}
+
+	if err = os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	now := time.Now()
+	f, err := os.CreateTemp(dir, fmt.Sprintf("crash-%s-*.log", now.Format("20060102-150405")))
+	if err != nil {
+		return "", fmt.Errorf("failed to create crash report: %w", err)
+	}
+	defer f.Close()
+
+	// The command may have panicked before it started running, so it is
+	// resolved from the arguments to redact the secret flags it defines.
+	args := c.arguments()
+	cmd, _, err := c.command.Find(args)
+	if err != nil {
+		cmd = c.command
+	}
+
+	flags := pflag.NewFlagSet(cmd.Name(), pflag.ContinueOnError)
+	flags.AddFlagSet(cmd.Flags())
+	flags.AddFlagSet(cmd.InheritedFlags())
+
+	var b strings.Builder
+	_, _ = fmt.Fprintf(&b, "%s crashed at %s\n\n", c.cli, now.Format(time.RFC3339))
+	_, _ = fmt.Fprintf(&b, "Version:    %s\n", c.version.Version())
+	_, _ = fmt.Fprintf(&b, "Build date: %s\n", c.version.BuildDate())
+	_, _ = fmt.Fprintf(&b, "Git commit: %s\n", c.version.GitCommit())
+	_, _ = fmt.Fprintf(&b, "Platform:   %s\n\n", c.version.Platform())
+	_, _ = fmt.Fprintf(&b, "Go version: %s\n", runtime.Version())
+	_, _ = fmt.Fprintf(&b, "OS/Arch:    %s/%s\n", runtime.GOOS, runtime.GOARCH)
+	_, _ = fmt.Fprintf(&b, "CPUs:       %d\n", runtime.NumCPU())
+	_, _ = fmt.Fprintf(&b, "Goroutines: %d\n\n", runtime.NumGoroutine())
+	_, _ = fmt.Fprintf(&b, "Command:    %s\n", cmd.CommandPath())
+	_, _ = fmt.Fprintf(&b, "Arguments:  %s\n\n", strings.Join(redactArgs(flags, args), " "))
+	_, _ = fmt.Fprintf(&b, "%s\n\n%s", e, e.stack)
+
+	if _, err = f.WriteString(b.String()); err != nil {
+		return "", fmt.Errorf("failed to write crash report: %w", err)
+	}
+
+	return filepath.Clean(f.Name()), nil
+}
+
+// arguments returns the arguments the command is executed with.
+func (c *Command[T]) arguments() []string {
+	if c.args != nil {
+		return c.args
+	}
+	return os.Args[1:]
+}
+
+// redactArgs returns a copy of args with the values of the flags marked with
+// cmdutils.MarkFlagSecret replaced. Flags are parsed like pflag does, so
+// values in shorthand clusters such as -vtSECRET are redacted as well.
+func redactArgs(flags *pflag.FlagSet, args []string) []string {
+	args = append([]string(nil), args...)
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--":
+			return args
+		case strings.HasPrefix(arg, "--"):
+			name, _, hasValue := strings.Cut(arg[2:], "=")
+			f := flags.Lookup(name)
+			switch {
+			case f == nil:
+			case hasValue:
+				if isSecret(f) {
+					args[i] = "--" + name + "=" + redacted
+				}
+			case f.NoOptDefVal == "" && i+1 < len(args):
+				// The next argument is the value.
+				i++
+				if isSecret(f) {
+					args[i] = redacted
+				}
+			}
+		case len(arg) > 1 && arg[0] == '-':
+			i = redactShorthands(flags, args, i)
+		}
+	}
+
+	return args
+}
+
+// redactShorthands redacts the value of a secret flag in the cluster of
+// shorthand flags args[i], and returns the index of the last argument of the
+// cluster, which is the next one if it holds the value of the last flag.
+func redactShorthands(flags *pflag.FlagSet, args []string, i int) int {
+	arg := args[i]
+	for j := 1; j < len(arg); j++ {
+		f := flags.ShorthandLookup(arg[j : j+1])
+		if f == nil {
+			return i
+		}
+
+		// The value of a flag is either after "=", the rest of the
+		// cluster or the next argument, unless it has a default.
+		rest := arg[j+1:]
+		switch {
+		case strings.HasPrefix(rest, "="):
+			if isSecret(f) {
+				args[i] = arg[:j+2] + redacted
+			}
+			return i
+		case f.NoOptDefVal != "":
+			continue
+		case rest != "":
+			if isSecret(f) {
+				args[i] = arg[:j+1] + redacted
+			}
+			return i
+		case i+1 < len(args):
+			if isSecret(f) {
+				args[i+1] = redacted
+			}
+			return i + 1
+		}
+	}
+
+	return i
+}
+
+// isSecret reports whether f is marked with cmdutils.MarkFlagSecret.
+func isSecret(f *pflag.Flag) bool {
+	return len(f.Annotations[cmdutils.SecretFlagAnnotation]) > 0
+}
+
+// printPanic prints the message of an execution that panicked, with its
+// stack in debug mode. With the JSON format, the message is printed as an
+// error envelope.
+func (c *Command[T]) printPanic(e *panicError) {
+	message := fmt.Sprintf("This is a bug in %s, please report it.", c.cli)
+
+	if c.format == printer.JSON {
+		envelope := struct {
+			Error       string `json:"error"`
+			Message     string `json:"message"`
+			Report      string `json:"report,omitempty"`
+			ReportError string `json:"report_error,omitempty"`
+		}{
+			Error:   e.Error(),
+			Message: message,
+			Report:  e.report,
+		}
+		if e.reportErr != nil {
+			envelope.ReportError = e.reportErr.Error()
+		}
+
+		b, _ := json.Marshal(envelope)
+		_, _ = fmt.Fprintf(c.stderr, "%s\n", b)
+		return
+	}
+
+	_, _ = fmt.Fprintf(c.stderr, "Error: %s\n\n", e)
+	_, _ = fmt.Fprintf(c.stderr, "%s\n", message)
+
+	if e.reportErr != nil {
+		_, _ = fmt.Fprintf(c.stderr, "The crash report could not be written: %s\n\n%s", e.reportErr, e.stack)
+		return
+	}
+	_, _ = fmt.Fprintf(c.stderr, "A crash report was written to %s, please include it in the report.\n", e.report)
+
+	if c.debug {
+		_, _ = fmt.Fprintf(c.stderr, "\n%s", e.stack)
+	}
+}
@@ -0,0 +1,207 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/loopholelabs/cmdutils"
+	"github.com/loopholelabs/cmdutils/pkg/config"
+)
+
+// fillMissing wraps cmd and all of its subcommands so that, when the printer
+// is able to prompt, the user is asked for missing required arguments and
+// flags instead of failing.
+func (c *Command[T]) fillMissing(cmd *cobra.Command, ch *cmdutils.Helper[T]) {
+	for _, sub := range cmd.Commands() {
+		c.fillMissing(sub, ch)
+	}
+
+	// Persistent hooks of a parent get the arguments of the command.
+	c.withFilledArgs(cmd)
+
+	if !cmd.Runnable() {
+		return
+	}
+
+	validateArgs := cmd.Args
+	if validateArgs == nil {
+		validateArgs = cobra.ArbitraryArgs
+	}
+
+	// Args are validated before required flags, so this is the last chance
+	// to provide both.
+	cmd.Args = func(cmd *cobra.Command, args []string) error {
+		// Prompting requires the printer, which isn't set up yet.
+		if err := c.initialize(cmd, ch); err != nil {
+			return err
+		}
+
+		err := validateArgs(cmd, args)
+
+		var missingErr *cmdutils.MissingArgsError
+		if err != nil && !errors.As(err, &missingErr) {
+			return err
+		}
+
+		if ch.Printer == nil || !ch.Printer.Interactive() {
+			return err
+		}
+
+		if missingErr != nil {
+			values, err := promptArgs(cmd, ch, args, missingErr.Missing)
+			if err != nil {
+				return err
+			}
+
+			// The slice is built once and shared by every hook, without
+			// writing to the backing array of cobra's arguments.
+			c.filledArgs = slices.Concat(args, values)
+		}
+
+		return promptFlags(cmd, ch)
+	}
+}
+
+// withFilledArgs wraps the run and hook functions of cmd, so that they get
+// the positional arguments including the ones the user was prompted for.
+func (c *Command[T]) withFilledArgs(cmd *cobra.Command) {
+	hooks := []*func(*cobra.Command, []string){
+		&cmd.PersistentPreRun, &cmd.PreRun, &cmd.Run, &cmd.PostRun, &cmd.PersistentPostRun,
+	}
+	for _, hook := range hooks {
+		if fn := *hook; fn != nil {
+			*hook = func(cmd *cobra.Command, args []string) {
+				fn(cmd, c.argsWithFilled(args))
+			}
+		}
+	}
+
+	hooksE := []*func(*cobra.Command, []string) error{
+		&cmd.PersistentPreRunE, &cmd.PreRunE, &cmd.RunE, &cmd.PostRunE, &cmd.PersistentPostRunE,
+	}
+	for _, hook := range hooksE {
+		if fn := *hook; fn != nil {
+			*hook = func(cmd *cobra.Command, args []string) error {
+				return fn(cmd, c.argsWithFilled(args))
+			}
+		}
+	}
+}
+
+// argsWithFilled returns the positional arguments including the ones the
+// user was prompted for, or args if there were none.
+func (c *Command[T]) argsWithFilled(args []string) []string {
+	if c.filledArgs != nil {
+		return c.filledArgs
+	}
+	return args
+}
+
+// promptArgs asks the user for the values of the missing positional
+// arguments.
+func promptArgs[T config.Config](cmd *cobra.Command, ch *cmdutils.Helper[T], args []string, missing []string) ([]string, error) {
+	var values []string
+	for _, name := range missing {
+		prev := append(append([]string{}, args...), values...)
+		options := completions(cmd, cmd.ValidArgsFunction, prev)
+		if len(options) == 0 {
+			options = cmd.ValidArgs
+		}
+
+		value, err := promptValue(cmd, ch, "", name, options, false)
+		if err != nil {
+			return nil, err
+		}
+		values = append(values, value)
+	}
+
+	return values, nil
+}
+
+// promptFlags asks the user for the values of required flags that weren't
+// set.
+func promptFlags[T config.Config](cmd *cobra.Command, ch *cmdutils.Helper[T]) error {
+	var missing []*pflag.Flag
+	cmd.Flags().VisitAll(func(f *pflag.Flag) {
+		required := f.Annotations[cobra.BashCompOneRequiredFlag]
+		if len(required) > 0 && required[0] == "true" && !f.Changed {
+			missing = append(missing, f)
+		}
+	})
+
+	for _, f := range missing {
+		var options []string
+		if fn, ok := cmd.GetFlagCompletionFunc(f.Name); ok {
+			options = completions(cmd, fn, cmd.Flags().Args())
+		}
+
+		message := f.Usage
+		if message == "" {
+			message = f.Name
+		}
+
+		secret := len(f.Annotations[cmdutils.SecretFlagAnnotation]) > 0
+		value, err := promptValue(cmd, ch, f.Name, message, options, secret)
+		if err != nil {
+			return err
+		}
+
+		if err := cmd.Flags().Set(f.Name, value); err != nil {
+			return fmt.Errorf("invalid value for flag --%s: %w", f.Name, err)
+		}
+	}
+
+	return nil
+}
+
+func promptValue[T config.Config](cmd *cobra.Command, ch *cmdutils.Helper[T], flag, message string, options []string, secret bool) (string, error) {
+	ctx := cmd.Context()
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	switch {
+	case secret:
+		return ch.Printer.Password(ctx, flag, message, required)
+	case len(options) > 0:
+		return ch.Printer.Select(ctx, flag, message, options, "")
+	}
+
+	return ch.Printer.Input(ctx, flag, message, "", required)
+}
+
+func required(value string) error {
+	if strings.TrimSpace(value) == "" {
+		return errors.New("a value is required")
+	}
+	return nil
+}
+
+// completions returns the values suggested by a completion function, without
+// their descriptions.
+func completions(cmd *cobra.Command, fn func(*cobra.Command, []string, string) ([]string, cobra.ShellCompDirective), args []string) []string {
+	if fn == nil {
+		return nil
+	}
+
+	values, directive := fn(cmd, args, "")
+	if directive&cobra.ShellCompDirectiveError != 0 {
+		return nil
+	}
+
+	options := make([]string, 0, len(values))
+	for _, v := range values {
+		v, _, _ = strings.Cut(v, "\t")
+		options = append(options, v)
+	}
+
+	return options
+}
@@ -0,0 +1,268 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/loopholelabs/cmdutils/pkg/config"
+)
+
+// latestLogName is the name of the symlink to the log file of the latest
+// invocation.
+const latestLogName = "latest"
+
+// rotatedSuffix matches the timestamp appended to the name of rotated log
+// files, followed by a counter if a log file was already rotated at the same
+// time.
+const rotatedSuffix = `-\d{8}-\d{6}\.\d{3}(?:-\d+)?`
+
+// logFile is a log sink that appends to a file, rotates it and removes old
+// log files according to a config.LogPolicy.
+type logFile struct {
+	mu     sync.Mutex
+	path   string
+	policy config.LogPolicy
+
+	// pattern matches the names of the log files that are removed by the
+	// policy. For log files of invocations, its first group is the PID of
+	// the invocation and its second group the timestamp of a rotated file.
+	pattern    *regexp.Regexp
+	invocation bool
+
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+// openLogFile opens the log file at path for appending.
+func openLogFile(path string, policy config.LogPolicy) (*logFile, error) {
+	ext := filepath.Ext(path)
+	l := &logFile{
+		path:    path,
+		policy:  policy,
+		pattern: regexp.MustCompile("^" + regexp.QuoteMeta(strings.TrimSuffix(filepath.Base(path), ext)) + rotatedSuffix + regexp.QuoteMeta(ext) + "$"),
+	}
+
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+
+	return l, nil
+}
+
+// openInvocationLogFile opens a new log file for this invocation in dir and
+// points the latest symlink at it.
+func openInvocationLogFile(dir string, cli string, policy config.LogPolicy) (*logFile, error) {
+	name := fmt.Sprintf("%s-%s-%d.log", cli, time.Now().Format("20060102-150405"), os.Getpid())
+	l := &logFile{
+		path:       filepath.Join(dir, name),
+		policy:     policy,
+		pattern:    regexp.MustCompile("^" + regexp.QuoteMeta(cli) + `-\d{8}-\d{6}-(\d+)(` + rotatedSuffix + `)?\.log$`),
+		invocation: true,
+	}
+
+	if err := l.open(); err != nil {
+		return nil, err
+	}
+
+	// The symlink is a convenience, it may not be supported by the
+	// platform.
+	tmp := filepath.Join(dir, fmt.Sprintf(".%s-%d", latestLogName, os.Getpid()))
+	_ = os.Remove(tmp)
+	if err := os.Symlink(name, tmp); err == nil {
+		if err = os.Rename(tmp, filepath.Join(dir, latestLogName)); err != nil {
+			_ = os.Remove(tmp)
+		}
+	}
+
+	return l, nil
+}
+
+// open opens the log file, rotating it first if needed, and removes old log
+// files.
+func (l *logFile) open() error {
+	if err := os.MkdirAll(filepath.Dir(l.path), 0700); err != nil {
+		return fmt.Errorf("failed to create log directory: %w", err)
+	}
+
+	l.opened = time.Now()
+	if info, err := os.Stat(l.path); err == nil {
+		l.size = info.Size()
+		l.opened = info.ModTime()
+		if l.shouldRotate(0) {
+			// A log file that can't be rotated is still appended to, and
+			// old log files are still removed.
+			if err := l.rotate(); err != nil {
+				if l.file == nil {
+					return err
+				}
+				l.removeOld()
+			}
+			return nil
+		}
+	}
+
+	if err := l.openFile(); err != nil {
+		return err
+	}
+
+	l.removeOld()
+
+	return nil
+}
+
+// openFile opens the log file for appending.
+func (l *logFile) openFile() error {
+	f, err := os.OpenFile(l.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open log file: %w", err)
+	}
+	l.file = f
+	return nil
+}
+
+// shouldRotate returns whether the log file must be rotated before n bytes
+// are written to it.
+func (l *logFile) shouldRotate(n int) bool {
+	if l.size == 0 {
+		return false
+	}
+	if l.policy.MaxSize > 0 && l.size+int64(n) > l.policy.MaxSize {
+		return true
+	}
+	return l.policy.RotateAge > 0 && time.Since(l.opened) > l.policy.RotateAge
+}
+
+// rotate renames the log file with a timestamp and opens a new one. If the
+// log file can't be renamed, it is reopened so that logging continues, and
+// rotation is tried again on the next write.
+func (l *logFile) rotate() error {
+	if l.file != nil {
+		if err := l.file.Close(); err != nil {
+			return fmt.Errorf("failed to close log file: %w", err)
+		}
+		l.file = nil
+	}
+
+	if err := os.Rename(l.path, l.rotatedPath(time.Now())); err != nil {
+		if l.openFile() == nil {
+			if info, err := l.file.Stat(); err == nil {
+				l.size = info.Size()
+			}
+		}
+		return fmt.Errorf("failed to rotate log file: %w", err)
+	}
+	l.size = 0
+
+	return l.open()
+}
+
+// rotatedPath returns the path the log file is renamed to when it is rotated
+// at now, which doesn't exist yet so that rotated log files are never
+// overwritten.
+func (l *logFile) rotatedPath(now time.Time) string {
+	ext := filepath.Ext(l.path)
+	base := strings.TrimSuffix(l.path, ext) + "-" + now.Format("20060102-150405.000")
+
+	rotated := base + ext
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(rotated); err != nil {
+			return rotated
+		}
+		rotated = fmt.Sprintf("%s-%d%s", base, i, ext)
+	}
+}
+
+// removeOld removes the log files that aren't retained by the policy. Errors
+// are ignored, the files are removed on a later invocation.
+func (l *logFile) removeOld() {
+	if l.policy.MaxFiles <= 0 && l.policy.MaxAge <= 0 {
+		return
+	}
+
+	dir := filepath.Dir(l.path)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	type logInfo struct {
+		path    string
+		modTime time.Time
+	}
+
+	var logs []logInfo
+	for _, e := range entries {
+		name := e.Name()
+		if !e.Type().IsRegular() || name == filepath.Base(l.path) {
+			continue
+		}
+
+		match := l.pattern.FindStringSubmatch(name)
+		if match == nil {
+			continue
+		}
+
+		// Other invocations that are still running keep their log file.
+		if l.invocation && match[2] == "" {
+			if pid, err := strconv.Atoi(match[1]); err == nil && processRunning(pid) {
+				continue
+			}
+		}
+
+		info, err := e.Info()
+		if err != nil {
+			continue
+		}
+		logs = append(logs, logInfo{path: filepath.Join(dir, name), modTime: info.ModTime()})
+	}
+
+	// Newest first, the current log file counts towards MaxFiles.
+	sort.Slice(logs, func(i, j int) bool { return logs[i].modTime.After(logs[j].modTime) })
+	for i, log := range logs {
+		if (l.policy.MaxFiles > 0 && i+1 >= l.policy.MaxFiles) || (l.policy.MaxAge > 0 && time.Since(log.modTime) > l.policy.MaxAge) {
+			_ = os.Remove(log.path)
+		}
+	}
+}
+
+func (l *logFile) Write(p []byte) (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return 0, os.ErrClosed
+	}
+
+	if l.shouldRotate(len(p)) {
+		if err := l.rotate(); err != nil && l.file == nil {
+			return 0, err
+		}
+	}
+
+	n, err := l.file.Write(p)
+	l.size += int64(n)
+	return n, err
+}
+
+func (l *logFile) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.file == nil {
+		return nil
+	}
+
+	err := l.file.Close()
+	l.file = nil
+	return err
+}
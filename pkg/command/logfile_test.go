@@ -0,0 +1,229 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/loopholelabs/cmdutils/pkg/config"
+)
+
+func writeLog(t *testing.T, path string, policy config.LogPolicy, lines ...string) {
+	t.Helper()
+
+	l, err := openLogFile(path, policy)
+	require.NoError(t, err)
+	for _, line := range lines {
+		_, err = l.Write([]byte(line))
+		require.NoError(t, err)
+	}
+	require.NoError(t, l.Close())
+}
+
+func TestLogFileAppend(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "logs", "test.log")
+
+	writeLog(t, path, config.LogPolicy{}, "first\n")
+	writeLog(t, path, config.LogPolicy{}, "second\n")
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "first\nsecond\n", string(b))
+}
+
+func TestLogFileRotation(t *testing.T) {
+	t.Run("size", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "test.log")
+		writeLog(t, path, config.LogPolicy{MaxSize: 10}, "first\n", "second\n")
+
+		b, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "second\n", string(b))
+
+		rotated, err := filepath.Glob(filepath.Join(filepath.Dir(path), "test-*.log"))
+		require.NoError(t, err)
+		require.Len(t, rotated, 1)
+
+		b, err = os.ReadFile(rotated[0])
+		require.NoError(t, err)
+		require.Equal(t, "first\n", string(b))
+	})
+
+	t.Run("age", func(t *testing.T) {
+		t.Parallel()
+
+		path := filepath.Join(t.TempDir(), "test.log")
+		writeLog(t, path, config.LogPolicy{}, "first\n")
+
+		old := time.Now().Add(-2 * time.Hour)
+		require.NoError(t, os.Chtimes(path, old, old))
+
+		writeLog(t, path, config.LogPolicy{RotateAge: time.Hour}, "second\n")
+
+		b, err := os.ReadFile(path)
+		require.NoError(t, err)
+		require.Equal(t, "second\n", string(b))
+
+		rotated, err := filepath.Glob(filepath.Join(filepath.Dir(path), "test-*.log"))
+		require.NoError(t, err)
+		require.Len(t, rotated, 1)
+	})
+}
+
+func TestLogFileRotatedPath(t *testing.T) {
+	dir := t.TempDir()
+
+	l := &logFile{path: filepath.Join(dir, "test.log")}
+	now := time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)
+
+	// Log files rotated at the same time are numbered.
+	for _, name := range []string{"test-20240101-000000.000.log", "test-20240101-000000.000-1.log", "test-20240101-000000.000-2.log"} {
+		rotated := l.rotatedPath(now)
+		require.Equal(t, filepath.Join(dir, name), rotated)
+		require.NoError(t, os.WriteFile(rotated, nil, 0600))
+	}
+
+	pattern := regexp.MustCompile("^test" + rotatedSuffix + `\.log$`)
+	require.True(t, pattern.MatchString("test-20240101-000000.000-2.log"))
+}
+
+func TestLogFileRotationFailure(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "test.log")
+
+	l, err := openLogFile(path, config.LogPolicy{MaxSize: 10})
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = l.Close() })
+
+	_, err = l.Write([]byte("first\n"))
+	require.NoError(t, err)
+
+	// The log file can't be renamed, so it is opened again.
+	require.NoError(t, os.Remove(path))
+	_, err = l.Write([]byte("second\n"))
+	require.NoError(t, err)
+
+	b, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "second\n", string(b))
+
+	// Rotation is tried again on the next write.
+	_, err = l.Write([]byte("third\n"))
+	require.NoError(t, err)
+
+	b, err = os.ReadFile(path)
+	require.NoError(t, err)
+	require.Equal(t, "third\n", string(b))
+
+	rotated, err := filepath.Glob(filepath.Join(filepath.Dir(path), "test-*.log"))
+	require.NoError(t, err)
+	require.Len(t, rotated, 1)
+}
+
+func TestLogFileRetention(t *testing.T) {
+	dir := t.TempDir()
+
+	// Old log files, newest first.
+	names := []string{
+		"test-20240101-000003.000.log",
+		"test-20240101-000002.000.log",
+		"test-20240101-000001.000.log",
+		"test-20240101-000000.000.log",
+	}
+	for i, name := range names {
+		path := filepath.Join(dir, name)
+		require.NoError(t, os.WriteFile(path, []byte(name), 0600))
+
+		modTime := time.Now().Add(-time.Duration(i+1) * time.Hour)
+		require.NoError(t, os.Chtimes(path, modTime, modTime))
+	}
+
+	// Files of other logs are kept, however old they are.
+	old := time.Now().Add(-24 * time.Hour)
+	for _, name := range []string{"other.log", "test-bar.log", "test-bar-20240101-000000.000.log"} {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0600))
+		require.NoError(t, os.Chtimes(filepath.Join(dir, name), old, old))
+	}
+
+	writeLog(t, filepath.Join(dir, "test.log"), config.LogPolicy{MaxFiles: 3, MaxAge: 150 * time.Minute}, "line\n")
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	var remaining []string
+	for _, e := range entries {
+		remaining = append(remaining, e.Name())
+	}
+	require.ElementsMatch(t, []string{
+		"other.log", "test-bar.log", "test-bar-20240101-000000.000.log",
+		"test.log", "test-20240101-000003.000.log", "test-20240101-000002.000.log",
+	}, remaining)
+
+	writeLog(t, filepath.Join(dir, "test.log"), config.LogPolicy{MaxFiles: 1}, "line\n")
+	_, err = os.Stat(filepath.Join(dir, "test-20240101-000003.000.log"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestInvocationLogFileRetention(t *testing.T) {
+	dir := t.TempDir()
+
+	// The parent of the test is running, the PID of the other invocation
+	// is too large to be used.
+	running := fmt.Sprintf("test-20240101-000000-%d.log", os.Getppid())
+	names := []string{
+		running,
+		"test-20240101-000000-999999999.log",
+		"test-20240101-000000-999999999-20240101-000001.000.log",
+		"test-bar-20240101-000000-999999999.log",
+	}
+
+	old := time.Now().Add(-24 * time.Hour)
+	for _, name := range names {
+		require.NoError(t, os.WriteFile(filepath.Join(dir, name), nil, 0600))
+		require.NoError(t, os.Chtimes(filepath.Join(dir, name), old, old))
+	}
+
+	l, err := openInvocationLogFile(dir, "test", config.LogPolicy{MaxAge: time.Hour})
+	require.NoError(t, err)
+	require.NoError(t, l.Close())
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+
+	var remaining []string
+	for _, e := range entries {
+		remaining = append(remaining, e.Name())
+	}
+	require.ElementsMatch(t, []string{
+		running, "test-bar-20240101-000000-999999999.log",
+		filepath.Base(l.path), latestLogName,
+	}, remaining)
+}
+
+func TestInvocationLogFile(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := openInvocationLogFile(dir, "test", config.LogPolicy{})
+	require.NoError(t, err)
+	_, err = l.Write([]byte("line\n"))
+	require.NoError(t, err)
+	require.NoError(t, l.Close())
+
+	require.Regexp(t, `^test-\d{8}-\d{6}-\d+\.log$`, filepath.Base(l.path))
+
+	target, err := os.Readlink(filepath.Join(dir, latestLogName))
+	require.NoError(t, err)
+	require.Equal(t, filepath.Base(l.path), target)
+
+	b, err := os.ReadFile(filepath.Join(dir, latestLogName))
+	require.NoError(t, err)
+	require.Equal(t, "line\n", string(b))
+}
@@ -0,0 +1,373 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/rs/zerolog"
+
+	"github.com/loopholelabs/logging"
+	"github.com/loopholelabs/logging/types"
+
+	"github.com/loopholelabs/cmdutils"
+	"github.com/loopholelabs/cmdutils/pkg/printer"
+)
+
+// logFormat is the format of the logs written to a destination.
+type logFormat string
+
+const (
+	// logFormatText writes logs as key=value pairs.
+	logFormatText logFormat = "text"
+
+	// logFormatJSON writes logs as JSON objects, one per line.
+	logFormatJSON logFormat = "json"
+
+	// logFormatConsole writes logs in a format that is easy to read by
+	// humans, colored on terminals.
+	logFormatConsole logFormat = "console"
+)
+
+var logFormats = []string{string(logFormatText), string(logFormatJSON), string(logFormatConsole)}
+
+func (f *logFormat) String() string { return string(*f) }
+
+func (f *logFormat) Set(s string) error {
+	switch v := logFormat(strings.ToLower(s)); v {
+	case logFormatText, logFormatJSON, logFormatConsole:
+		*f = v
+		return nil
+	}
+
+	return fmt.Errorf("failed to parse log format: %q. Valid values: %+v", s, logFormats)
+}
+
+func (f *logFormat) Type() string { return "string" }
+
+// logLevels is a pflag.Value for a log level followed by the levels of
+// components, such as "info,http=debug,db=trace".
+type logLevels struct {
+	level      *types.Level
+	components *map[string]types.Level
+}
+
+func (l *logLevels) String() string {
+	if l.level == nil {
+		return ""
+	}
+
+	var components []string
+	for name, level := range *l.components {
+		components = append(components, name+"="+strings.ToLower(level.String()))
+	}
+	slices.Sort(components)
+
+	return strings.Join(append([]string{strings.ToLower(l.level.String())}, components...), ",")
+}
+
+func (l *logLevels) Set(s string) error {
+	level := *l.level
+	var components map[string]types.Level
+	for _, part := range strings.Split(s, ",") {
+		name, value, ok := strings.Cut(strings.TrimSpace(part), "=")
+		if !ok {
+			if err := level.Set(name); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var componentLevel types.Level
+		if err := componentLevel.Set(strings.TrimSpace(value)); err != nil {
+			return fmt.Errorf("%w for component %q", err, name)
+		}
+		if components == nil {
+			components = make(map[string]types.Level)
+		}
+		components[strings.TrimSpace(name)] = componentLevel
+	}
+
+	*l.level = level
+	*l.components = components
+	return nil
+}
+
+func (l *logLevels) Type() string { return "log level" }
+
+// componentLogger sets the level of the loggers of components that have
+// their own level. Components are named after the sources passed to
+// SubLogger, joined with colons for nested components, such as "http:client".
+type componentLogger struct {
+	types.RootLogger
+	component string
+	levels    map[string]types.Level
+}
+
+func (l *componentLogger) SubLogger(source string) types.Logger {
+	component := source
+	if l.component != "" {
+		component = l.component + ":" + source
+	}
+
+	sub, ok := l.RootLogger.SubLogger(source).(types.RootLogger)
+	if !ok {
+		return l.RootLogger.SubLogger(source)
+	}
+
+	// Nested components inherit the level of their parent.
+	if level, ok := l.levels[component]; ok {
+		sub.SetLevel(level)
+	}
+
+	return &componentLogger{RootLogger: sub, component: component, levels: l.levels}
+}
+
+func (l *componentLogger) With() types.Context {
+	return &componentContext{Context: l.RootLogger.With(), component: l.component, levels: l.levels}
+}
+
+// componentContext adds fields to the logger of a component, keeping the
+// levels of the components of the logger it returns.
+type componentContext struct {
+	types.Context
+	component string
+	levels    map[string]types.Level
+}
+
+func (c *componentContext) Logger() types.Logger {
+	l := c.Context.Logger()
+	root, ok := l.(types.RootLogger)
+	if !ok {
+		return l
+	}
+	return &componentLogger{RootLogger: root, component: c.component, levels: c.levels}
+}
+
+func (c *componentContext) Str(key string, val string) types.Context {
+	c.Context = c.Context.Str(key, val)
+	return c
+}
+
+func (c *componentContext) Bool(key string, val bool) types.Context {
+	c.Context = c.Context.Bool(key, val)
+	return c
+}
+
+func (c *componentContext) Int(key string, val int) types.Context {
+	c.Context = c.Context.Int(key, val)
+	return c
+}
+
+func (c *componentContext) Int8(key string, val int8) types.Context {
+	c.Context = c.Context.Int8(key, val)
+	return c
+}
+
+func (c *componentContext) Int16(key string, val int16) types.Context {
+	c.Context = c.Context.Int16(key, val)
+	return c
+}
+
+func (c *componentContext) Int32(key string, val int32) types.Context {
+	c.Context = c.Context.Int32(key, val)
+	return c
+}
+
+func (c *componentContext) Int64(key string, val int64) types.Context {
+	c.Context = c.Context.Int64(key, val)
+	return c
+}
+
+func (c *componentContext) Uint(key string, val uint) types.Context {
+	c.Context = c.Context.Uint(key, val)
+	return c
+}
+
+func (c *componentContext) Uint8(key string, val uint8) types.Context {
+	c.Context = c.Context.Uint8(key, val)
+	return c
+}
+
+func (c *componentContext) Uint16(key string, val uint16) types.Context {
+	c.Context = c.Context.Uint16(key, val)
+	return c
+}
+
+func (c *componentContext) Uint32(key string, val uint32) types.Context {
+	c.Context = c.Context.Uint32(key, val)
+	return c
+}
+
+func (c *componentContext) Uint64(key string, val uint64) types.Context {
+	c.Context = c.Context.Uint64(key, val)
+	return c
+}
+
+func (c *componentContext) Float32(key string, val float32) types.Context {
+	c.Context = c.Context.Float32(key, val)
+	return c
+}
+
+func (c *componentContext) Float64(key string, val float64) types.Context {
+	c.Context = c.Context.Float64(key, val)
+	return c
+}
+
+func (c *componentContext) IPAddr(key string, ipAddr net.IP) types.Context {
+	c.Context = c.Context.IPAddr(key, ipAddr)
+	return c
+}
+
+func (c *componentContext) MACAddr(key string, macAddr net.HardwareAddr) types.Context {
+	c.Context = c.Context.MACAddr(key, macAddr)
+	return c
+}
+
+func (c *componentContext) Err(err error) types.Context {
+	c.Context = c.Context.Err(err)
+	return c
+}
+
+// The destinations logs can be written to.
+const (
+	logOutputFile   = "file"
+	logOutputStderr = "stderr"
+	logOutputSyslog = "syslog"
+)
+
+var logOutputs = []string{logOutputFile, logOutputStderr, logOutputSyslog}
+
+// DefaultSyslogAddress is the unix domain socket of the local syslog daemon
+// on most systems.
+const DefaultSyslogAddress = "/dev/log"
+
+// newLogger returns a logger that writes to w in the given format, with
+// the given levels for components.
+func newLogger(format logFormat, source string, level types.Level, components map[string]types.Level, w io.Writer, noColor bool) types.RootLogger {
+	var l types.RootLogger
+	switch format {
+	case logFormatJSON:
+		l = logging.New(logging.Zerolog, source, w)
+	case logFormatConsole:
+		l = logging.New(logging.Zerolog, source, zerolog.ConsoleWriter{Out: w, NoColor: noColor})
+	default:
+		l = logging.New(logging.Slog, source, w)
+	}
+	l.SetLevel(level)
+
+	if len(components) > 0 {
+		return &componentLogger{RootLogger: l, levels: components}
+	}
+	return l
+}
+
+// fixedLevelLogger is the logger of a destination with its own level, such
+// as the one set with --log-stderr-level, which SetLevel doesn't change.
+type fixedLevelLogger struct {
+	types.RootLogger
+}
+
+func (fixedLevelLogger) SetLevel(types.Level) {}
+
+// setupLogger sets the logger of ch, which writes to every log destination
+// with its own format and level. Without --log-output, logs are written to
+// the log file, and mirrored to stderr in debug mode, or to stderr if there
+// is no log file.
+func (c *Command[T]) setupLogger(ch *cmdutils.Helper[T]) error {
+	var outputs []string
+	for _, output := range c.viper.GetStringSlice("log-output") {
+		for _, o := range strings.Split(output, ",") {
+			if o = strings.ToLower(strings.TrimSpace(o)); o != "" {
+				outputs = append(outputs, o)
+			}
+		}
+	}
+	if len(outputs) == 0 {
+		switch {
+		case strings.TrimSpace(c.logFile) == "":
+			outputs = []string{logOutputStderr}
+		case ch.Debug():
+			outputs = []string{logOutputFile, logOutputStderr}
+		default:
+			outputs = []string{logOutputFile}
+		}
+	}
+
+	format := c.logFormat
+	if format == "" {
+		format = logFormatText
+		if c.format == printer.JSON {
+			format = logFormatJSON
+		}
+	}
+
+	stderrFormat := c.logStderrFormat
+	if stderrFormat == "" {
+		stderrFormat = format
+	}
+
+	stderrLevel, stderrComponents := c.logLevel, c.logComponents
+	stderrFixed := c.viper.IsSet("log-stderr-level")
+	if stderrFixed {
+		stderrLevel, stderrComponents = c.logStderrLevel, c.logStderrComponents
+	}
+
+	source := strings.ToLower(c.cli)
+	seen := make(map[string]bool)
+	var loggers []types.RootLogger
+	for _, output := range outputs {
+		if seen[output] {
+			continue
+		}
+		seen[output] = true
+
+		switch output {
+		case logOutputFile:
+			if strings.TrimSpace(c.logFile) == "" {
+				return errors.New("logging to a file requires a log file, set it with --log")
+			}
+
+			var f *logFile
+			var err error
+			if policy := c.config.LogPolicy(); policy.PerInvocation && c.logFile == c.defaultLogFile {
+				f, err = openInvocationLogFile(filepath.Dir(c.logFile), source, policy)
+			} else {
+				f, err = openLogFile(c.logFile, policy)
+			}
+			if err != nil {
+				return err
+			}
+			c.addLogCloser(f.Close)
+
+			loggers = append(loggers, newLogger(format, source, c.logLevel, c.logComponents, f, true))
+		case logOutputStderr:
+			noColor := c.noColor || !printer.ColorEnabled(c.stderr)
+			l := newLogger(stderrFormat, source, stderrLevel, stderrComponents, c.stderr, noColor)
+			if stderrFixed {
+				l = fixedLevelLogger{RootLogger: l}
+			}
+			loggers = append(loggers, l)
+		case logOutputSyslog:
+			w, err := dialSyslog(c.logSyslogAddress, source)
+			if err != nil {
+				return err
+			}
+			c.addLogCloser(w.Close)
+
+			loggers = append(loggers, newLogger(format, source, c.logLevel, c.logComponents, w, true))
+		default:
+			return fmt.Errorf("failed to parse log output: %q. Valid values: %+v", output, logOutputs)
+		}
+	}
+
+	ch.Logger = newMultiLogger(loggers...)
+
+	return nil
+}
@@ -0,0 +1,57 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/loopholelabs/cmdutils"
+	"github.com/loopholelabs/cmdutils/pkg/config"
+)
+
+// RunFunc runs a command with the given arguments. The context of the
+// execution is available from cmd.Context().
+type RunFunc[T config.Config] func(ch *cmdutils.Helper[T], cmd *cobra.Command, args []string) error
+
+// Middleware wraps the execution of every command, for behaviour such as
+// authentication checks, telemetry or audit logging. It runs after the
+// configuration has been validated and returns a RunFunc that usually calls
+// next.
+type Middleware[T config.Config] func(next RunFunc[T]) RunFunc[T]
+
+// applyMiddleware wraps the run function of cmd and all of its subcommands
+// with the middleware of the command. The first middleware is the outermost
+// one.
+func (c *Command[T]) applyMiddleware(cmd *cobra.Command, ch *cmdutils.Helper[T]) {
+	for _, sub := range cmd.Commands() {
+		c.applyMiddleware(sub, ch)
+	}
+
+	if len(c.middleware) == 0 || !cmd.Runnable() {
+		return
+	}
+
+	var run RunFunc[T]
+	switch {
+	case cmd.RunE != nil:
+		runE := cmd.RunE
+		run = func(_ *cmdutils.Helper[T], cmd *cobra.Command, args []string) error {
+			return runE(cmd, args)
+		}
+	case cmd.Run != nil:
+		runFn := cmd.Run
+		run = func(_ *cmdutils.Helper[T], cmd *cobra.Command, args []string) error {
+			runFn(cmd, args)
+			return nil
+		}
+	}
+
+	for i := len(c.middleware) - 1; i >= 0; i-- {
+		run = c.middleware[i](run)
+	}
+
+	cmd.Run = nil
+	cmd.RunE = func(cmd *cobra.Command, args []string) error {
+		return run(ch, cmd, args)
+	}
+}
@@ -0,0 +1,364 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"net"
+
+	"github.com/loopholelabs/logging/types"
+)
+
+var _ types.RootLogger = (*multiLogger)(nil)
+
+// multiLogger writes every log to several loggers, so that each destination
+// can have its own level and format.
+type multiLogger struct {
+	loggers []types.Logger
+}
+
+// newMultiLogger returns a logger that writes to all the given loggers, or
+// the only logger if there is just one.
+func newMultiLogger(loggers ...types.RootLogger) types.RootLogger {
+	if len(loggers) == 1 {
+		return loggers[0]
+	}
+
+	m := &multiLogger{loggers: make([]types.Logger, len(loggers))}
+	for i, l := range loggers {
+		m.loggers[i] = l
+	}
+	return m
+}
+
+// SetLevel sets the level of every logger, except the loggers of
+// destinations with their own level, which keep it.
+func (m *multiLogger) SetLevel(level types.Level) {
+	for _, l := range m.loggers {
+		if root, ok := l.(types.RootLogger); ok {
+			root.SetLevel(level)
+		}
+	}
+}
+
+// Level returns the most verbose level of the loggers.
+func (m *multiLogger) Level() types.Level {
+	level := types.FatalLevel
+	for _, l := range m.loggers {
+		level = max(level, l.Level())
+	}
+	return level
+}
+
+func (m *multiLogger) SubLogger(source string) types.Logger {
+	s := &multiLogger{loggers: make([]types.Logger, len(m.loggers))}
+	for i, l := range m.loggers {
+		s.loggers[i] = l.SubLogger(source)
+	}
+	return s
+}
+
+func (m *multiLogger) With() types.Context {
+	c := &multiContext{contexts: make([]types.Context, len(m.loggers))}
+	for i, l := range m.loggers {
+		c.contexts[i] = l.With()
+	}
+	return c
+}
+
+func (m *multiLogger) event(event func(types.Logger) types.Event) types.Event {
+	e := make(multiEvent, len(m.loggers))
+	for i, l := range m.loggers {
+		e[i] = event(l)
+	}
+	return e
+}
+
+func (m *multiLogger) Fatal() types.Event {
+	return m.event(types.Logger.Fatal)
+}
+
+func (m *multiLogger) Error() types.Event {
+	return m.event(types.Logger.Error)
+}
+
+func (m *multiLogger) Warn() types.Event {
+	return m.event(types.Logger.Warn)
+}
+
+func (m *multiLogger) Info() types.Event {
+	return m.event(types.Logger.Info)
+}
+
+func (m *multiLogger) Debug() types.Event {
+	return m.event(types.Logger.Debug)
+}
+
+func (m *multiLogger) Trace() types.Event {
+	return m.event(types.Logger.Trace)
+}
+
+// multiContext adds fields to the loggers of a multiLogger.
+type multiContext struct {
+	contexts []types.Context
+}
+
+func (c *multiContext) Logger() types.Logger {
+	m := &multiLogger{loggers: make([]types.Logger, len(c.contexts))}
+	for i, ctx := range c.contexts {
+		m.loggers[i] = ctx.Logger()
+	}
+	return m
+}
+
+func (c *multiContext) Str(key string, val string) types.Context {
+	for i, ctx := range c.contexts {
+		c.contexts[i] = ctx.Str(key, val)
+	}
+	return c
+}
+
+func (c *multiContext) Bool(key string, val bool) types.Context {
+	for i, ctx := range c.contexts {
+		c.contexts[i] = ctx.Bool(key, val)
+	}
+	return c
+}
+
+func (c *multiContext) Int(key string, val int) types.Context {
+	for i, ctx := range c.contexts {
+		c.contexts[i] = ctx.Int(key, val)
+	}
+	return c
+}
+
+func (c *multiContext) Int8(key string, val int8) types.Context {
+	for i, ctx := range c.contexts {
+		c.contexts[i] = ctx.Int8(key, val)
+	}
+	return c
+}
+
+func (c *multiContext) Int16(key string, val int16) types.Context {
+	for i, ctx := range c.contexts {
+		c.contexts[i] = ctx.Int16(key, val)
+	}
+	return c
+}
+
+func (c *multiContext) Int32(key string, val int32) types.Context {
+	for i, ctx := range c.contexts {
+		c.contexts[i] = ctx.Int32(key, val)
+	}
+	return c
+}
+
+func (c *multiContext) Int64(key string, val int64) types.Context {
+	for i, ctx := range c.contexts {
+		c.contexts[i] = ctx.Int64(key, val)
+	}
+	return c
+}
+
+func (c *multiContext) Uint(key string, val uint) types.Context {
+	for i, ctx := range c.contexts {
+		c.contexts[i] = ctx.Uint(key, val)
+	}
+	return c
+}
+
+func (c *multiContext) Uint8(key string, val uint8) types.Context {
+	for i, ctx := range c.contexts {
+		c.contexts[i] = ctx.Uint8(key, val)
+	}
+	return c
+}
+
+func (c *multiContext) Uint16(key string, val uint16) types.Context {
+	for i, ctx := range c.contexts {
+		c.contexts[i] = ctx.Uint16(key, val)
+	}
+	return c
+}
+
+func (c *multiContext) Uint32(key string, val uint32) types.Context {
+	for i, ctx := range c.contexts {
+		c.contexts[i] = ctx.Uint32(key, val)
+	}
+	return c
+}
+
+func (c *multiContext) Uint64(key string, val uint64) types.Context {
+	for i, ctx := range c.contexts {
+		c.contexts[i] = ctx.Uint64(key, val)
+	}
+	return c
+}
+
+func (c *multiContext) Float32(key string, val float32) types.Context {
+	for i, ctx := range c.contexts {
+		c.contexts[i] = ctx.Float32(key, val)
+	}
+	return c
+}
+
+func (c *multiContext) Float64(key string, val float64) types.Context {
+	for i, ctx := range c.contexts {
+		c.contexts[i] = ctx.Float64(key, val)
+	}
+	return c
+}
+
+func (c *multiContext) IPAddr(key string, ipAddr net.IP) types.Context {
+	for i, ctx := range c.contexts {
+		c.contexts[i] = ctx.IPAddr(key, ipAddr)
+	}
+	return c
+}
+
+func (c *multiContext) MACAddr(key string, macAddr net.HardwareAddr) types.Context {
+	for i, ctx := range c.contexts {
+		c.contexts[i] = ctx.MACAddr(key, macAddr)
+	}
+	return c
+}
+
+func (c *multiContext) Err(err error) types.Context {
+	for i, ctx := range c.contexts {
+		c.contexts[i] = ctx.Err(err)
+	}
+	return c
+}
+
+// multiEvent is a log event of every logger of a multiLogger.
+type multiEvent []types.Event
+
+func (e multiEvent) Msg(msg string) {
+	for _, event := range e {
+		event.Msg(msg)
+	}
+}
+
+func (e multiEvent) Msgf(format string, args ...interface{}) {
+	for _, event := range e {
+		event.Msgf(format, args...)
+	}
+}
+
+func (e multiEvent) Str(key string, val string) types.Event {
+	for i, event := range e {
+		e[i] = event.Str(key, val)
+	}
+	return e
+}
+
+func (e multiEvent) Bool(key string, val bool) types.Event {
+	for i, event := range e {
+		e[i] = event.Bool(key, val)
+	}
+	return e
+}
+
+func (e multiEvent) Int(key string, val int) types.Event {
+	for i, event := range e {
+		e[i] = event.Int(key, val)
+	}
+	return e
+}
+
+func (e multiEvent) Int8(key string, val int8) types.Event {
+	for i, event := range e {
+		e[i] = event.Int8(key, val)
+	}
+	return e
+}
+
+func (e multiEvent) Int16(key string, val int16) types.Event {
+	for i, event := range e {
+		e[i] = event.Int16(key, val)
+	}
+	return e
+}
+
+func (e multiEvent) Int32(key string, val int32) types.Event {
+	for i, event := range e {
+		e[i] = event.Int32(key, val)
+	}
+	return e
+}
+
+func (e multiEvent) Int64(key string, val int64) types.Event {
+	for i, event := range e {
+		e[i] = event.Int64(key, val)
+	}
+	return e
+}
+
+func (e multiEvent) Uint(key string, val uint) types.Event {
+	for i, event := range e {
+		e[i] = event.Uint(key, val)
+	}
+	return e
+}
+
+func (e multiEvent) Uint8(key string, val uint8) types.Event {
+	for i, event := range e {
+		e[i] = event.Uint8(key, val)
+	}
+	return e
+}
+
+func (e multiEvent) Uint16(key string, val uint16) types.Event {
+	for i, event := range e {
+		e[i] = event.Uint16(key, val)
+	}
+	return e
+}
+
+func (e multiEvent) Uint32(key string, val uint32) types.Event {
+	for i, event := range e {
+		e[i] = event.Uint32(key, val)
+	}
+	return e
+}
+
+func (e multiEvent) Uint64(key string, val uint64) types.Event {
+	for i, event := range e {
+		e[i] = event.Uint64(key, val)
+	}
+	return e
+}
+
+func (e multiEvent) Float32(key string, val float32) types.Event {
+	for i, event := range e {
+		e[i] = event.Float32(key, val)
+	}
+	return e
+}
+
+func (e multiEvent) Float64(key string, val float64) types.Event {
+	for i, event := range e {
+		e[i] = event.Float64(key, val)
+	}
+	return e
+}
+
+func (e multiEvent) IPAddr(key string, ipAddr net.IP) types.Event {
+	for i, event := range e {
+		e[i] = event.IPAddr(key, ipAddr)
+	}
+	return e
+}
+
+func (e multiEvent) MACAddr(key string, macAddr net.HardwareAddr) types.Event {
+	for i, event := range e {
+		e[i] = event.MACAddr(key, macAddr)
+	}
+	return e
+}
+
+func (e multiEvent) Err(err error) types.Event {
+	for i, event := range e {
+		e[i] = event.Err(err)
+	}
+	return e
+}
@@ -0,0 +1,96 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// outputFile is an io.Writer for a file that is written to a temporary
+// location and only moved to its final path once the command succeeds, so
+// readers never observe partially written output. The temporary file is
+// created on the first write, or on commit if nothing was written.
+type outputFile struct {
+	path string
+	file *os.File
+	err  error
+}
+
+func newOutputFile(path string) *outputFile {
+	return &outputFile{
+		path: path,
+	}
+}
+
+// create creates the temporary file next to the final path, so that it can
+// be renamed atomically.
+func (f *outputFile) create() error {
+	dir, base := filepath.Split(f.path)
+	if dir == "" {
+		dir = "."
+	}
+
+	f.file, f.err = os.CreateTemp(dir, fmt.Sprintf(".%s.*.tmp", base))
+	if f.err != nil {
+		f.err = fmt.Errorf("failed to create output file: %w", f.err)
+	}
+	return f.err
+}
+
+func (f *outputFile) Write(p []byte) (int, error) {
+	if f.err != nil {
+		return 0, f.err
+	}
+
+	if f.file == nil {
+		if err := f.create(); err != nil {
+			return 0, err
+		}
+	}
+
+	return f.file.Write(p)
+}
+
+// Commit closes the temporary file and renames it to its final path. A
+// command that printed nothing leaves an empty file, so that a file from an
+// earlier run is never mistaken for its output.
+func (f *outputFile) Commit() error {
+	if f.err != nil {
+		return f.err
+	}
+
+	if f.file == nil {
+		if err := f.create(); err != nil {
+			return err
+		}
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(f.path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	err := errors.Join(f.file.Chmod(mode), f.file.Sync(), f.file.Close())
+	if err == nil {
+		err = os.Rename(f.file.Name(), f.path)
+	}
+	if err != nil {
+		_ = os.Remove(f.file.Name())
+		return fmt.Errorf("failed to write output file: %w", err)
+	}
+
+	return nil
+}
+
+// Abort closes and removes the temporary file, leaving any existing file at
+// the final path untouched.
+func (f *outputFile) Abort() error {
+	if f.file == nil {
+		return nil
+	}
+
+	return errors.Join(f.file.Close(), os.Remove(f.file.Name()))
+}
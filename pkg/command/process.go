@@ -0,0 +1,16 @@
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !windows && !plan9
+
+package command
+
+import (
+	"errors"
+	"syscall"
+)
+
+// processRunning reports whether a process with the given PID is running.
+func processRunning(pid int) bool {
+	err := syscall.Kill(pid, 0)
+	return err == nil || errors.Is(err, syscall.EPERM)
+}
@@ -0,0 +1,16 @@
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build plan9
+
+package command
+
+import (
+	"fmt"
+	"os"
+)
+
+// processRunning reports whether a process with the given PID is running.
+func processRunning(pid int) bool {
+	_, err := os.Stat(fmt.Sprintf("/proc/%d", pid))
+	return err == nil
+}
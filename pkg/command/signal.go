@@ -0,0 +1,158 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/loopholelabs/cmdutils"
+	"github.com/loopholelabs/cmdutils/pkg/printer"
+)
+
+// DefaultShutdownGracePeriod is a sensible grace period for HandleSignals.
+const DefaultShutdownGracePeriod = 10 * time.Second
+
+// HandleSignals makes Execute cancel the context of the command on SIGINT or
+// SIGTERM. The command and its shutdown hooks then have gracePeriod to
+// return, after which the process exits with CanceledExitCode or
+// TerminatedExitCode, as it does on a second signal. A gracePeriod of zero or
+// less waits for the command indefinitely.
+func (c *Command[T]) HandleSignals(gracePeriod time.Duration) {
+	c.handleSignals = true
+	c.gracePeriod = gracePeriod
+}
+
+// notifySignals relays SIGINT and SIGTERM to ch until stop is called.
+func notifySignals(ch chan<- os.Signal) (stop func()) {
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+	return func() { signal.Stop(ch) }
+}
+
+// signalExitCode returns the exit code of a process stopped by sig.
+func signalExitCode(sig os.Signal) int {
+	if sig == syscall.SIGTERM {
+		return cmdutils.TerminatedExitCode
+	}
+	return cmdutils.CanceledExitCode
+}
+
+// watchSignals returns a context that is canceled on the first signal, if
+// signal handling is enabled. The returned function stops watching and
+// returns the signal that was received, if any.
+func (c *Command[T]) watchSignals(ctx context.Context) (context.Context, func() os.Signal) {
+	if !c.handleSignals {
+		return ctx, func() os.Signal { return nil }
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	signals := make(chan os.Signal, 2)
+	stopNotify := c.notifySignals(signals)
+	done := make(chan struct{})
+
+	var (
+		mu       sync.Mutex
+		received os.Signal
+	)
+
+	c.signalMu.Lock()
+	c.shutdownDeadline = time.Time{}
+	c.signalPrinter = nil
+	c.signalMu.Unlock()
+
+	go func() {
+		var sig os.Signal
+		select {
+		case sig = <-signals:
+		case <-done:
+			return
+		}
+
+		mu.Lock()
+		received = sig
+		mu.Unlock()
+
+		c.notify("\nReceived %s, shutting down. Send it again to exit immediately.", sig)
+
+		// The command and the shutdown hooks share the grace period.
+		var timeout <-chan time.Time
+		if c.gracePeriod > 0 {
+			c.signalMu.Lock()
+			c.shutdownDeadline = time.Now().Add(c.gracePeriod)
+			c.signalMu.Unlock()
+
+			timer := time.NewTimer(c.gracePeriod)
+			defer timer.Stop()
+			timeout = timer.C
+		}
+		cancel()
+
+		select {
+		case sig = <-signals:
+			c.notify("Received %s again, exiting", sig)
+		case <-timeout:
+			c.notify("Did not shut down within %s, exiting", c.gracePeriod)
+		case <-done:
+			return
+		}
+
+		// Deferred functions don't run on exit.
+		c.closeLogs()
+		c.exit(signalExitCode(sig))
+	}()
+
+	return ctx, func() os.Signal {
+		close(done)
+		stopNotify()
+		cancel()
+
+		mu.Lock()
+		defer mu.Unlock()
+		return received
+	}
+}
+
+// notify prints a notice about a signal to stderr. With --format=json it is
+// a warning event of the printer, like every other message on stderr.
+func (c *Command[T]) notify(format string, a ...interface{}) {
+	c.signalMu.Lock()
+	p := c.signalPrinter
+	c.signalMu.Unlock()
+
+	msg := fmt.Sprintf(format, a...)
+	if p != nil && p.Format() == printer.JSON {
+		p.Warning("%s", strings.TrimLeft(msg, "\n"))
+		return
+	}
+	_, _ = fmt.Fprintln(c.stderr, msg)
+}
+
+// shutdown calls the shutdown hooks of ch with a context that expires at the
+// end of the grace period, which starts with the signal if one was received.
+func (c *Command[T]) shutdown(ctx context.Context, ch *cmdutils.Helper[T]) error {
+	ctx = context.WithoutCancel(ctx)
+	if c.handleSignals && c.gracePeriod > 0 {
+		c.signalMu.Lock()
+		deadline := c.shutdownDeadline
+		c.signalMu.Unlock()
+
+		if deadline.IsZero() {
+			deadline = time.Now().Add(c.gracePeriod)
+		}
+
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+
+	if err := ch.Shutdown(ctx); err != nil {
+		return fmt.Errorf("shutdown: %w", err)
+	}
+	return nil
+}
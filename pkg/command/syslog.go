@@ -0,0 +1,24 @@
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build !windows && !plan9
+
+package command
+
+import (
+	"fmt"
+	"io"
+	"log/syslog"
+)
+
+// dialSyslog connects to the syslog daemon listening on the unix domain
+// socket at address.
+func dialSyslog(address string, tag string) (io.WriteCloser, error) {
+	w, err := syslog.Dial("unixgram", address, syslog.LOG_INFO|syslog.LOG_USER, tag)
+	if err != nil {
+		w, err = syslog.Dial("unix", address, syslog.LOG_INFO|syslog.LOG_USER, tag)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to syslog: %w", err)
+	}
+	return w, nil
+}
@@ -0,0 +1,15 @@
+// SPDX-License-Identifier: Apache-2.0
+
+//go:build windows || plan9
+
+package command
+
+import (
+	"errors"
+	"io"
+)
+
+// dialSyslog isn't supported on this platform.
+func dialSyslog(address string, tag string) (io.WriteCloser, error) {
+	return nil, errors.New("syslog is not supported on this platform")
+}
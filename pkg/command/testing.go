@@ -9,6 +9,8 @@ import (
 	"testing"
 
 	"github.com/loopholelabs/cmdutils"
+	"github.com/loopholelabs/cmdutils/pkg/config"
+	"github.com/loopholelabs/cmdutils/pkg/printer"
 	"github.com/loopholelabs/cmdutils/pkg/version"
 	"github.com/spf13/cobra"
 	"github.com/spf13/pflag"
@@ -67,10 +69,16 @@ func (h *TestCommandHarness) setupCommandRun(rootCmd *cobra.Command, ch *cmdutil
 }
 
 func (h *TestCommandHarness) Execute(ctx context.Context, args []string) int {
-	h.cmd.command.SetArgs(args)
+	h.cmd.args = args
 	return h.cmd.Execute(ctx, Noninteractive)
 }
 
+// SetPrinter replaces the printer the commands get, for example with a
+// printertest.Recorder to answer prompts.
+func (h *TestCommandHarness) SetPrinter(p printer.Interface) {
+	h.cmd.testPrinter = p
+}
+
 func (h *TestCommandHarness) Stdout() string {
 	return h.stdout.String()
 }
@@ -82,11 +90,23 @@ func (h *TestCommandHarness) Stderr() string {
 type TestConfig struct {
 	cfgFile string
 	logFile string
+	logDir  string
 
 	// Common configuration values.
 	Format  string
 	Debug   bool
 	NoColor bool `mapstructure:"no-color"`
+
+	// Name is set by the global --name flag, which is required when
+	// requireName is set.
+	Name        string
+	requireName bool
+
+	// validate is called by Validate, if set.
+	validate func(*TestConfig) error
+
+	// logPolicy is returned by LogPolicy.
+	logPolicy config.LogPolicy
 }
 
 func NewTestConfigFn(cfgFile string, logFile string) func() *TestConfig {
@@ -94,18 +114,35 @@ func NewTestConfigFn(cfgFile string, logFile string) func() *TestConfig {
 		return &TestConfig{
 			cfgFile: cfgFile,
 			logFile: logFile,
+			logDir:  filepath.Dir(logFile),
 		}
 	}
 }
 
-func (_ *TestConfig) RootPersistentFlags(flags *pflag.FlagSet)     { return }
-func (_ *TestConfig) GlobalRequiredFlags(cmd *cobra.Command) error { return nil }
-func (_ *TestConfig) Validate() error                              { return nil }
-func (c *TestConfig) DefaultConfigDir() (string, error)            { return filepath.Dir(c.cfgFile), nil }
-func (c *TestConfig) DefaultConfigFile() string                    { return c.cfgFile }
-func (c *TestConfig) DefaultLogDir() (string, error)               { return filepath.Dir(c.logFile), nil }
-func (c *TestConfig) DefaultLogFile() string                       { return c.logFile }
-func (c *TestConfig) SetConfigFile(cfg string)                     { c.cfgFile = cfg }
-func (c *TestConfig) GetConfigFile() string                        { return c.cfgFile }
-func (c *TestConfig) SetLogFile(l string)                          { c.logFile = l }
-func (c *TestConfig) GetLogFile() string                           { return c.logFile }
+func (c *TestConfig) RootPersistentFlags(flags *pflag.FlagSet) {
+	flags.StringVar(&c.Name, "name", "", "Name of the test")
+}
+
+func (c *TestConfig) GlobalRequiredFlags(cmd *cobra.Command) error {
+	if c.requireName {
+		return cmd.MarkPersistentFlagRequired("name")
+	}
+	return nil
+}
+
+func (c *TestConfig) Validate() error {
+	if c.validate != nil {
+		return c.validate(c)
+	}
+	return nil
+}
+
+func (c *TestConfig) DefaultConfigDir() (string, error) { return filepath.Dir(c.cfgFile), nil }
+func (c *TestConfig) DefaultConfigFile() string         { return c.cfgFile }
+func (c *TestConfig) DefaultLogDir() (string, error)    { return c.logDir, nil }
+func (c *TestConfig) DefaultLogFile() string            { return c.logFile }
+func (c *TestConfig) SetConfigFile(cfg string)          { c.cfgFile = cfg }
+func (c *TestConfig) GetConfigFile() string             { return c.cfgFile }
+func (c *TestConfig) SetLogFile(l string)               { c.logFile = l }
+func (c *TestConfig) GetLogFile() string                { return c.logFile }
+func (c *TestConfig) LogPolicy() config.LogPolicy       { return c.logPolicy }
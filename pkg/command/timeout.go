@@ -0,0 +1,44 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/loopholelabs/cmdutils"
+)
+
+// setTimeout replaces the context of cmd with one that expires after the
+// duration set by the --timeout flag or configuration, if any.
+func (c *Command[T]) setTimeout(cmd *cobra.Command) {
+	c.timeout = c.viper.GetDuration("timeout")
+	if c.timeout <= 0 {
+		return
+	}
+
+	c.timeoutCtx, c.cancelTimeout = context.WithTimeout(cmd.Context(), c.timeout)
+	cmd.SetContext(c.timeoutCtx)
+}
+
+// timeoutError releases the timeout of the execution and, if the command
+// failed after it expired, replaces err with an error that reports the
+// timeout and exits with TimeoutExitCode.
+func (c *Command[T]) timeoutError(err error) error {
+	if c.timeoutCtx == nil {
+		return err
+	}
+	defer c.cancelTimeout()
+
+	if err == nil || !errors.Is(c.timeoutCtx.Err(), context.DeadlineExceeded) {
+		return err
+	}
+
+	return &cmdutils.Error{
+		Msg:      fmt.Sprintf("timed out after %s", c.timeout),
+		ExitCode: cmdutils.TimeoutExitCode,
+	}
+}
@@ -0,0 +1,90 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package command
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	"github.com/loopholelabs/cmdutils"
+)
+
+// validateBeforeHooks wraps the persistent pre-run hooks of the subcommands
+// of cmd, so that the configuration is validated after it has been merged
+// from the config file, the environment, flags and prompts, before any hook,
+// middleware or run function sees it. Cobra only calls the closest
+// persistent pre-run hook, the one of the root command validates in runCmd.
+func (c *Command[T]) validateBeforeHooks(cmd *cobra.Command) {
+	for _, sub := range cmd.Commands() {
+		c.validateBeforeHooks(sub)
+	}
+
+	preRunE, preRun := cmd.PersistentPreRunE, cmd.PersistentPreRun
+	if !cmd.HasParent() || (preRunE == nil && preRun == nil) {
+		return
+	}
+
+	cmd.PersistentPreRun = nil
+	cmd.PersistentPreRunE = func(cmd *cobra.Command, args []string) error {
+		if err := c.validate(cmd); err != nil {
+			return err
+		}
+
+		if preRunE != nil {
+			return preRunE(cmd, args)
+		}
+		preRun(cmd, args)
+		return nil
+	}
+}
+
+// validate calls Config.Validate, unless cmd skips validation.
+func (c *Command[T]) validate(cmd *cobra.Command) error {
+	if skipValidation(cmd) {
+		return nil
+	}
+
+	if err := c.config.Validate(); err != nil {
+		return &cmdutils.Error{
+			Msg:      fmt.Sprintf("invalid configuration: %s", err),
+			ExitCode: cmdutils.InvalidInputExitCode,
+		}
+	}
+
+	return nil
+}
+
+// skipValidation reports whether cmd or one of its parents is marked with
+// cmdutils.SkipValidation. Cobra's help and completion commands are always
+// skipped.
+func skipValidation(cmd *cobra.Command) bool {
+	for p := cmd; p != nil; p = p.Parent() {
+		if _, ok := p.Annotations[cmdutils.SkipValidationAnnotation]; ok {
+			return true
+		}
+
+		if p.HasParent() && !p.Parent().HasParent() {
+			switch p.Name() {
+			case "help", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// skipRequiredFlags stops cobra from requiring the inherited flags marked
+// as required by Config.GlobalRequiredFlags, for commands that skip
+// validation.
+func skipRequiredFlags(cmd *cobra.Command) {
+	if !skipValidation(cmd) {
+		return
+	}
+
+	cmd.InheritedFlags().VisitAll(func(f *pflag.Flag) {
+		delete(f.Annotations, cobra.BashCompOneRequiredFlag)
+	})
+}
@@ -21,4 +21,5 @@ type Config interface {
 	GetConfigFile() string
 	SetLogFile(logFile string)
 	GetLogFile() string
+	LogPolicy() LogPolicy
 }
@@ -0,0 +1,31 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package config
+
+import "time"
+
+// LogPolicy configures how log files are written, rotated and retained. The
+// zero value appends to the log file and never rotates or removes files.
+type LogPolicy struct {
+	// PerInvocation writes the logs of every invocation into a new file named
+	// <cli>-<timestamp>-<pid>.log in the directory of the default log file,
+	// and points a symlink named "latest" at it. It doesn't apply when the log
+	// file is set with the --log flag.
+	PerInvocation bool
+
+	// MaxSize rotates the log file before it grows larger than MaxSize
+	// bytes.
+	MaxSize int64
+
+	// RotateAge rotates the log file once it has been written to for longer
+	// than RotateAge. As the creation time of files isn't portable, the age
+	// of an existing file is counted from its last modification.
+	RotateAge time.Duration
+
+	// MaxFiles is the maximum number of log files, including the current one,
+	// that are kept. The oldest files are removed first.
+	MaxFiles int
+
+	// MaxAge removes log files that weren't modified for longer than MaxAge.
+	MaxAge time.Duration
+}
@@ -0,0 +1,307 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package printer
+
+import (
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/briandowns/spinner"
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/mattn/go-isatty"
+)
+
+// ColorDepth is the number of colours a terminal can display.
+type ColorDepth int
+
+const (
+	ColorDepthNone ColorDepth = iota
+	ColorDepth16
+	ColorDepth256
+	ColorDepthTrueColor
+)
+
+// ciEnvs are environment variables set by common CI systems.
+var ciEnvs = []string{
+	"CI",
+	"BUILD_NUMBER",
+	"BUILDKITE",
+	"CIRCLECI",
+	"GITHUB_ACTIONS",
+	"GITLAB_CI",
+	"JENKINS_URL",
+	"TEAMCITY_VERSION",
+	"TF_BUILD",
+	"TRAVIS",
+}
+
+var (
+	asciiSpinnerCharset = spinner.CharSets[9]
+
+	asciiSymbols = Symbols{
+		Success: "[ok]",
+		Warning: "[warn]",
+		Error:   "[error]",
+		Info:    "[info]",
+	}
+
+	accessibleSymbols = Symbols{
+		Success: "Success:",
+		Warning: "Warning:",
+		Error:   "Error:",
+		Info:    "Info:",
+	}
+)
+
+// Capabilities describes what an output is able to display.
+type Capabilities struct {
+	// TTY is true if the output is a terminal.
+	TTY bool
+
+	// Dumb is true if the terminal doesn't support cursor movement.
+	Dumb bool
+
+	// CI is true when running in a continuous integration environment.
+	CI bool
+
+	// Unicode is true if the output can display non-ASCII characters.
+	Unicode bool
+
+	// ColorDepth is the number of colours the output can display. Themes
+	// only use the 16 standard colours, so any depth but ColorDepthNone
+	// displays them.
+	ColorDepth ColorDepth
+
+	// Hyperlinks is true if the terminal supports OSC 8 hyperlinks.
+	Hyperlinks bool
+
+	// Accessible is true if output should be friendly to screen readers,
+	// without animations, cursor movement or symbols.
+	Accessible bool
+}
+
+// DetectCapabilities probes the capabilities of the given output from its
+// file descriptor and the environment.
+func DetectCapabilities(out io.Writer) Capabilities {
+	caps := Capabilities{
+		Dumb: os.Getenv("TERM") == "dumb",
+	}
+
+	for _, env := range ciEnvs {
+		if v := os.Getenv(env); v != "" && v != "false" && v != "0" {
+			caps.CI = true
+			break
+		}
+	}
+
+	caps.TTY = isTerminal(out)
+	caps.Unicode = !caps.Dumb && unicodeLocale()
+
+	if colorEnabled(caps.TTY) {
+		caps.ColorDepth = colorDepth()
+	}
+
+	caps.Hyperlinks = caps.TTY && !caps.Dumb && hyperlinks()
+
+	return caps
+}
+
+// isTerminal reports whether out is a terminal.
+func isTerminal(out io.Writer) bool {
+	f, ok := out.(interface{ Fd() uintptr })
+	if !ok {
+		return false
+	}
+
+	return isatty.IsTerminal(f.Fd()) || isatty.IsCygwinTerminal(f.Fd())
+}
+
+// unicodeLocale reports whether the locale uses UTF-8. Windows Terminal
+// always supports it.
+func unicodeLocale() bool {
+	if os.Getenv("WT_SESSION") != "" {
+		return true
+	}
+
+	// The first variable that is set defines the character encoding.
+	for _, env := range []string{"LC_ALL", "LC_CTYPE", "LANG"} {
+		if v := os.Getenv(env); v != "" {
+			v = strings.ToLower(v)
+			return strings.Contains(v, "utf-8") || strings.Contains(v, "utf8")
+		}
+	}
+
+	return false
+}
+
+// hyperlinks reports whether the terminal is known to support OSC 8
+// hyperlinks. FORCE_HYPERLINK overrides the detection.
+func hyperlinks() bool {
+	if v, ok := os.LookupEnv("FORCE_HYPERLINK"); ok {
+		return v != "0" && v != "false"
+	}
+
+	switch os.Getenv("TERM_PROGRAM") {
+	case "iTerm.app", "WezTerm", "vscode", "ghostty", "Hyper":
+		return true
+	}
+
+	switch os.Getenv("TERM") {
+	case "xterm-kitty", "alacritty", "foot", "xterm-ghostty":
+		return true
+	}
+
+	// VTE based terminals, such as GNOME Terminal, support hyperlinks
+	// since version 0.50.
+	if v, err := strconv.Atoi(os.Getenv("VTE_VERSION")); err == nil && v >= 5000 {
+		return true
+	}
+
+	return os.Getenv("WT_SESSION") != "" || os.Getenv("KONSOLE_VERSION") != ""
+}
+
+func colorDepth() ColorDepth {
+	switch strings.ToLower(os.Getenv("COLORTERM")) {
+	case "truecolor", "24bit":
+		return ColorDepthTrueColor
+	}
+
+	if strings.Contains(os.Getenv("TERM"), "256color") {
+		return ColorDepth256
+	}
+
+	return ColorDepth16
+}
+
+// CanAnimate reports whether the output can display animations such as
+// spinners, which redraw the current line. CI logs keep every frame, so
+// nothing is animated in CI.
+func (c Capabilities) CanAnimate() bool {
+	return c.TTY && !c.Dumb && !c.CI && !c.Accessible
+}
+
+// Color reports whether the output can display colours.
+func (c Capabilities) Color() bool {
+	return c.ColorDepth != ColorDepthNone
+}
+
+// SetAccessible enables or disables accessible mode, which replaces spinners
+// with plain status lines, uses ASCII table borders and spells out status
+// symbols.
+func (p *Printer) SetAccessible(accessible bool) {
+	p.accessible = accessible
+	p.resetCapabilities()
+}
+
+// SetNoColor disables colours, even if the outputs can display them.
+func (p *Printer) SetNoColor(noColor bool) {
+	p.noColor = noColor
+	p.resetCapabilities()
+}
+
+// Capabilities returns the capabilities of the output for human-readable
+// messages. They are probed once, until the output changes.
+func (p *Printer) Capabilities() Capabilities {
+	p.capsMu.Lock()
+	defer p.capsMu.Unlock()
+
+	if p.humanCaps == nil {
+		out := p.out()
+		if out == io.Discard {
+			out = color.Output
+		}
+
+		caps := p.detectCapabilities(out)
+		p.humanCaps = &caps
+	}
+
+	return *p.humanCaps
+}
+
+// resourceCapabilities returns the capabilities of the output for resources.
+func (p *Printer) resourceCapabilities() Capabilities {
+	p.capsMu.Lock()
+	defer p.capsMu.Unlock()
+
+	if p.resourceCaps == nil {
+		caps := p.detectCapabilities(p.resourceOutput())
+		p.resourceCaps = &caps
+	}
+
+	return *p.resourceCaps
+}
+
+func (p *Printer) detectCapabilities(out io.Writer) Capabilities {
+	caps := DetectCapabilities(out)
+	caps.Accessible = p.accessible
+	if p.noColor {
+		caps.ColorDepth = ColorDepthNone
+	}
+	return caps
+}
+
+// resetCapabilities makes the next calls probe the capabilities again.
+func (p *Printer) resetCapabilities() {
+	p.capsMu.Lock()
+	defer p.capsMu.Unlock()
+
+	p.humanCaps, p.resourceCaps = nil, nil
+}
+
+// Symbols returns the status symbols of the printer's theme, adapted to the
+// capabilities of the output.
+func (p *Printer) Symbols() Symbols {
+	caps := p.Capabilities()
+	switch {
+	case caps.Accessible:
+		return accessibleSymbols
+	case !caps.Unicode:
+		return asciiSymbols
+	}
+
+	return p.Theme().Symbols
+}
+
+// Emoji returns the given emoji if the output is a terminal that can display
+// it, and an empty string otherwise.
+func (p *Printer) Emoji(emoji string) string {
+	caps := p.Capabilities()
+	if caps.TTY && caps.Unicode && !caps.Accessible {
+		return emoji
+	}
+	return ""
+}
+
+// spinnerCharset returns the spinner frames of the printer's theme, adapted
+// to the capabilities of the output.
+func (p *Printer) spinnerCharset(caps Capabilities) []string {
+	theme := p.Theme()
+
+	charset := theme.Spinner.Charset
+	if !caps.Unicode {
+		charset = asciiSpinnerCharset
+	}
+
+	styled := make([]string, len(charset))
+	for i, c := range charset {
+		styled[i] = theme.Spinner.Style.Sprint(c)
+	}
+	return styled
+}
+
+// tableStyle returns the table style of the printer's theme, adapted to the
+// capabilities of the output the table is written to.
+func (p *Printer) tableStyle(caps Capabilities) table.Style {
+	if caps.Accessible {
+		return table.StyleDefault
+	}
+
+	style := p.themeFor(caps).tableStyle(caps.Color())
+	if !caps.Unicode {
+		style.Box = table.StyleBoxDefault
+	}
+	return style
+}
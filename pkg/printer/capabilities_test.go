@@ -0,0 +1,104 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package printer
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/stretchr/testify/require"
+)
+
+// clearCapabilityEnv unsets the environment variables used to detect
+// capabilities for the duration of the test.
+func clearCapabilityEnv(t *testing.T) {
+	envs := append([]string{
+		"TERM", "COLORTERM", "WT_SESSION", "LC_ALL", "LC_CTYPE", "LANG",
+		"NO_COLOR", "FORCE_COLOR", "CLICOLOR_FORCE",
+		"FORCE_HYPERLINK", "TERM_PROGRAM", "VTE_VERSION", "KONSOLE_VERSION",
+	}, ciEnvs...)
+	for _, env := range envs {
+		t.Setenv(env, "")
+		require.NoError(t, os.Unsetenv(env))
+	}
+}
+
+func TestDetectCapabilities(t *testing.T) {
+	clearCapabilityEnv(t)
+
+	var out bytes.Buffer
+	caps := DetectCapabilities(&out)
+	require.Equal(t, Capabilities{}, caps)
+	require.False(t, caps.CanAnimate())
+
+	t.Setenv("LANG", "en_US.UTF-8")
+	require.True(t, DetectCapabilities(&out).Unicode)
+
+	t.Setenv("LC_ALL", "C")
+	require.False(t, DetectCapabilities(&out).Unicode)
+
+	t.Setenv("LC_ALL", "")
+	t.Setenv("TERM", "dumb")
+	caps = DetectCapabilities(&out)
+	require.True(t, caps.Dumb)
+	require.False(t, caps.Unicode)
+
+	t.Setenv("TERM", "xterm-256color")
+	t.Setenv("FORCE_COLOR", "1")
+	require.Equal(t, ColorDepth256, DetectCapabilities(&out).ColorDepth)
+
+	t.Setenv("COLORTERM", "truecolor")
+	require.Equal(t, ColorDepthTrueColor, DetectCapabilities(&out).ColorDepth)
+
+	t.Setenv("GITHUB_ACTIONS", "true")
+	require.True(t, DetectCapabilities(&out).CI)
+
+	require.False(t, Capabilities{TTY: true, Dumb: true}.CanAnimate())
+	require.False(t, Capabilities{TTY: true, Accessible: true}.CanAnimate())
+	require.False(t, Capabilities{TTY: true, CI: true}.CanAnimate())
+	require.True(t, Capabilities{TTY: true}.CanAnimate())
+}
+
+func TestPrinterFallbacks(t *testing.T) {
+	clearCapabilityEnv(t)
+
+	format := Human
+	p := NewPrinter(&format)
+	p.SetHumanOutput(&bytes.Buffer{})
+	p.SetResourceOutput(&bytes.Buffer{})
+
+	require.Equal(t, asciiSymbols, p.Symbols())
+	require.Equal(t, table.StyleBoxDefault, p.tableStyle(p.resourceCapabilities()).Box)
+	require.Equal(t, asciiSpinnerCharset, p.spinnerCharset(p.Capabilities()))
+	require.Empty(t, p.Emoji("🚀"))
+
+	// Capabilities are probed again once the output changes.
+	t.Setenv("LANG", "en_US.UTF-8")
+	require.Equal(t, asciiSymbols, p.Symbols())
+	p.SetHumanOutput(&bytes.Buffer{})
+	require.Equal(t, DefaultTheme.Symbols, p.Symbols())
+
+	p.SetAccessible(true)
+	require.Equal(t, accessibleSymbols, p.Symbols())
+	require.Equal(t, table.StyleDefault, p.tableStyle(p.resourceCapabilities()))
+	require.False(t, p.Capabilities().CanAnimate())
+}
+
+func TestPrinterColors(t *testing.T) {
+	clearCapabilityEnv(t)
+	t.Setenv("FORCE_COLOR", "1")
+
+	format := Human
+	p := NewPrinter(&format)
+	p.SetHumanOutput(&bytes.Buffer{})
+	require.Equal(t, ColorDepth16, p.Capabilities().ColorDepth)
+	require.Equal(t, DefaultTheme, p.Theme())
+
+	p.SetNoColor(true)
+	require.False(t, p.Capabilities().Color())
+	require.Empty(t, p.Theme().Accent)
+	require.Empty(t, p.Theme().Spinner.Style)
+	require.Equal(t, DefaultTheme.Symbols, p.Theme().Symbols)
+}
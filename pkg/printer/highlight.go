@@ -0,0 +1,151 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package printer
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// shouldHighlight reports whether output with the given capabilities should
+// be syntax highlighted, which is only the case for colour enabled terminals.
+func shouldHighlight(caps Capabilities) bool {
+	return caps.TTY && caps.Color()
+}
+
+// highlightJSON colours the keys and values of the given valid JSON
+// document.
+func highlightJSON(b []byte, theme *Theme) []byte {
+	syntax := theme.Syntax
+
+	var out bytes.Buffer
+	out.Grow(len(b) * 2)
+
+	for i := 0; i < len(b); {
+		switch c := b[i]; {
+		case c == '"':
+			end := i + 1
+			for end < len(b) && b[end] != '"' {
+				if b[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end++
+			if end > len(b) {
+				end = len(b)
+			}
+
+			// A string followed by a colon is an object key.
+			next := end
+			for next < len(b) && isJSONSpace(b[next]) {
+				next++
+			}
+			if next < len(b) && b[next] == ':' {
+				out.WriteString(syntax.Key.Sprint(string(b[i:end])))
+			} else {
+				out.WriteString(syntax.String.Sprint(string(b[i:end])))
+			}
+			i = end
+		case c == '-' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(b) && strings.IndexByte("0123456789.eE+-", b[end]) != -1 {
+				end++
+			}
+			out.WriteString(syntax.Number.Sprint(string(b[i:end])))
+			i = end
+		case bytes.HasPrefix(b[i:], []byte("true")):
+			out.WriteString(syntax.Bool.Sprint("true"))
+			i += len("true")
+		case bytes.HasPrefix(b[i:], []byte("false")):
+			out.WriteString(syntax.Bool.Sprint("false"))
+			i += len("false")
+		case bytes.HasPrefix(b[i:], []byte("null")):
+			out.WriteString(syntax.Null.Sprint("null"))
+			i += len("null")
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+
+	return out.Bytes()
+}
+
+func isJSONSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+var (
+	// yamlLine splits a line of block style YAML into its indentation
+	// (including sequence markers), an optional mapping key and the value.
+	yamlLine   = regexp.MustCompile(`^(\s*(?:- )*)(?:("(?:[^"\\]|\\.)*"|'(?:[^']|'')*'|[^\s'"#\-][^:#]*?|-[^\s:#][^:#]*?):(?: |$))?(.*)$`)
+	yamlNumber = regexp.MustCompile(`^[-+]?(\.inf|\.Inf|\.INF|\.nan|\.NaN|\.NAN|0x[0-9a-fA-F]+|0o[0-7]+|[0-9][0-9_]*(\.[0-9]*)?([eE][-+]?[0-9]+)?|\.[0-9]+([eE][-+]?[0-9]+)?)$`)
+)
+
+// highlightYAML colours the keys and scalar values of the given YAML
+// document, as produced by yaml.Marshal.
+func highlightYAML(s string, theme *Theme) string {
+	syntax := theme.Syntax
+
+	lines := strings.Split(s, "\n")
+
+	// blockIndent is the indentation of the key that started a literal or
+	// folded block scalar, or -1 if the current line isn't part of one.
+	blockIndent := -1
+
+	for i, line := range lines {
+		indent := len(line) - len(strings.TrimLeft(line, " "))
+		if blockIndent >= 0 {
+			if strings.TrimSpace(line) == "" || indent > blockIndent {
+				lines[i] = syntax.String.Sprint(line)
+				continue
+			}
+			blockIndent = -1
+		}
+
+		m := yamlLine.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		prefix, key, value := m[1], m[2], m[3]
+
+		var b strings.Builder
+		b.WriteString(prefix)
+		if key != "" {
+			b.WriteString(syntax.Key.Sprint(key))
+			b.WriteString(":")
+			if value != "" {
+				b.WriteString(" ")
+			}
+		}
+
+		if strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">") {
+			blockIndent = indent
+			b.WriteString(value)
+		} else {
+			b.WriteString(highlightYAMLScalar(value, syntax))
+		}
+
+		lines[i] = b.String()
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func highlightYAMLScalar(v string, syntax SyntaxTheme) string {
+	switch {
+	case v == "", v == "[]", v == "{}":
+		return v
+	case v == "null", v == "~":
+		return syntax.Null.Sprint(v)
+	case v == "true", v == "false":
+		return syntax.Bool.Sprint(v)
+	case yamlNumber.MatchString(v):
+		return syntax.Number.Sprint(v)
+	}
+
+	return syntax.String.Sprint(v)
+}
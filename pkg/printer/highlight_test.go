@@ -0,0 +1,59 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package printer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestHighlightJSON(t *testing.T) {
+	in := []byte(`{
+  "name": "a \"quoted\": value",
+  "count": -1.5e3,
+  "ready": true,
+  "deleted": false,
+  "owner": null,
+  "tags": ["x"]
+}`)
+
+	syntax := DarkTheme.Syntax
+	out := highlightJSON(in, DarkTheme)
+	require.Contains(t, string(out), syntax.Key.Sprint(`"name"`))
+	require.Contains(t, string(out), syntax.String.Sprint(`"a \"quoted\": value"`))
+	require.Contains(t, string(out), syntax.Number.Sprint("-1.5e3"))
+	require.Contains(t, string(out), syntax.Bool.Sprint("true"))
+	require.Contains(t, string(out), syntax.Bool.Sprint("false"))
+	require.Contains(t, string(out), syntax.Null.Sprint("null"))
+	require.Contains(t, string(out), syntax.String.Sprint(`"x"`))
+
+	require.True(t, bytes.Equal(in, highlightJSON(in, DarkTheme.withoutColors())))
+}
+
+func TestHighlightYAML(t *testing.T) {
+	in := `name: http://example.com
+count: 42
+ready: true
+owner: null
+description: |
+    first: line
+    second line
+tags:
+    - x: 1
+    - "y"`
+
+	syntax := DarkTheme.Syntax
+	out := highlightYAML(in, DarkTheme)
+	require.Contains(t, out, syntax.Key.Sprint("name")+": "+syntax.String.Sprint("http://example.com"))
+	require.Contains(t, out, syntax.Key.Sprint("count")+": "+syntax.Number.Sprint("42"))
+	require.Contains(t, out, syntax.Key.Sprint("ready")+": "+syntax.Bool.Sprint("true"))
+	require.Contains(t, out, syntax.Key.Sprint("owner")+": "+syntax.Null.Sprint("null"))
+	require.Contains(t, out, syntax.String.Sprint("    first: line"))
+	require.Contains(t, out, syntax.Key.Sprint("tags")+":\n")
+	require.Contains(t, out, "    - "+syntax.Key.Sprint("x")+": "+syntax.Number.Sprint("1"))
+	require.Contains(t, out, "    - "+syntax.String.Sprint(`"y"`))
+
+	require.Equal(t, in, highlightYAML(in, DarkTheme.withoutColors()))
+}
@@ -0,0 +1,103 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package printer
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Interface is implemented by Printer and by fakes used to test commands,
+// such as printertest.Recorder. Commands should only depend on Interface, so
+// that they can be tested without a terminal.
+type Interface interface {
+	SetFormat(format Format)
+	SetResourceFormat(format Format)
+	SetQuiet(quiet bool)
+	SetInteractive(interactive bool)
+	SetAssumeYes(assumeYes bool)
+	SetAssumeYesHint(flag, env string)
+	SetPromptTimeout(timeout time.Duration)
+	SetNoColor(noColor bool)
+	SetAccessible(accessible bool)
+	SetTheme(theme *Theme)
+	SetContext(ctx context.Context)
+
+	// SetHumanOutput, SetResourceOutput and SetEventOutput redirect the
+	// human-readable messages, resources and JSON events.
+	SetHumanOutput(out io.Writer)
+	SetResourceOutput(out io.Writer)
+	SetEventOutput(out io.Writer)
+
+	// Format returns the output format.
+	Format() Format
+
+	// ResourceFormat returns the format of resources printed by
+	// PrintResource.
+	ResourceFormat() Format
+
+	// Quiet returns whether quiet mode is enabled.
+	Quiet() bool
+
+	// Interactive returns whether the user can be prompted for input.
+	Interactive() bool
+
+	// AssumeYes returns whether confirmations are answered automatically.
+	AssumeYes() bool
+
+	// Theme returns the theme used for human-readable output.
+	Theme() *Theme
+
+	// Capabilities returns the capabilities of the output for
+	// human-readable messages.
+	Capabilities() Capabilities
+
+	// Symbols returns the status symbols adapted to the output.
+	Symbols() Symbols
+
+	// Emoji returns the given emoji if the output can display it.
+	Emoji(emoji string) string
+
+	// Link returns a hyperlink to url with the given text.
+	Link(text, url string) string
+
+	// Out returns the output for human-readable text.
+	Out() io.Writer
+	Printf(format string, i ...interface{})
+	Println(i ...interface{})
+	Print(i ...interface{})
+
+	// Flush writes out any incomplete line printed to Out.
+	Flush()
+
+	Success(format string, a ...interface{})
+	Info(format string, a ...interface{})
+	Warning(format string, a ...interface{})
+	Failure(format string, a ...interface{})
+	Step(n, total int, format string, a ...interface{})
+	Hint(format string, a ...interface{})
+
+	PrintResource(v interface{}) error
+	PrintJSON(v interface{}) error
+	PrintYAML(v interface{}) error
+	PrintCSV(v interface{}) error
+	PrettyPrintJSON(b []byte) error
+
+	PrintProgress(message string) Progress
+	PrintProgressContext(ctx context.Context, message string) Progress
+
+	ConfirmCommand(confirmationName, commandShortName, confirmFailedName string) error
+	ConfirmCommandContext(ctx context.Context, confirmationName, commandShortName, confirmFailedName string) error
+
+	Input(ctx context.Context, flag, message, defaultValue string, validators ...Validator) (string, error)
+	Password(ctx context.Context, flag, message string, validators ...Validator) (string, error)
+	Select(ctx context.Context, flag, message string, options []string, defaultValue string) (string, error)
+	MultiSelect(ctx context.Context, flag, message string, options []string, defaultValues []string) ([]string, error)
+	Confirm(ctx context.Context, flag, message string, defaultValue bool) (bool, error)
+
+	// OpenBrowser opens url in the user's browser, or prints it.
+	OpenBrowser(url string) error
+}
+
+var _ Interface = (*Printer)(nil)
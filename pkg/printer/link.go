@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package printer
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Link returns a hyperlink to url with the given text if stdout is a
+// terminal that supports OSC 8 hyperlinks, and "text (url)" otherwise.
+//
+// Deprecated: Use Printer.Link, which probes the output the printer writes
+// to.
+func Link(text, url string) string {
+	return link(DetectCapabilities(os.Stdout), text, url)
+}
+
+// Link returns a hyperlink to url with the given text if the output for
+// human-readable messages supports OSC 8 hyperlinks, and "text (url)"
+// otherwise. Accessible mode always spells out the URL.
+func (p *Printer) Link(text, url string) string {
+	return link(p.Capabilities(), text, url)
+}
+
+func link(caps Capabilities, text, url string) string {
+	if text == "" {
+		text = url
+	}
+
+	if !caps.Hyperlinks || caps.Accessible {
+		if text == url {
+			return url
+		}
+		return text + " (" + url + ")"
+	}
+
+	return "\x1b]8;;" + url + "\x1b\\" + text + "\x1b]8;;\x1b\\"
+}
+
+// OpenBrowser opens url in the user's browser, using $BROWSER if it is set.
+// If the printer can't interact with the user, or the browser can't be
+// started, the URL is printed instead so it can be opened manually.
+func (p *Printer) OpenBrowser(url string) error {
+	if p.Interactive() {
+		if cmd := browserCommand(url); cmd != nil && cmd.Start() == nil {
+			// Reap the process once the browser, or the launcher, exits.
+			go func() { _ = cmd.Wait() }()
+			return nil
+		}
+	}
+
+	p.Info("Open %s in your browser", p.Link(url, url))
+	return nil
+}
+
+// browserCommand returns the command that opens url in a browser, or nil if
+// there is none.
+func browserCommand(url string) *exec.Cmd {
+	// $BROWSER is a list of commands separated by colons, of which the
+	// first one is used.
+	if browser, _, _ := strings.Cut(os.Getenv("BROWSER"), string(os.PathListSeparator)); browser != "" {
+		args := strings.Fields(browser)
+		return exec.Command(args[0], append(args[1:], url)...)
+	}
+
+	switch runtime.GOOS {
+	case "darwin":
+		return exec.Command("open", url)
+	case "windows":
+		return exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	}
+
+	if _, err := exec.LookPath("xdg-open"); err != nil {
+		return nil
+	}
+	return exec.Command("xdg-open", url)
+}
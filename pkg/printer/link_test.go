@@ -0,0 +1,80 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package printer
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jedib0t/go-pretty/v6/text"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLink(t *testing.T) {
+	url := "https://example.com/a"
+
+	caps := Capabilities{}
+	require.Equal(t, url, link(caps, "", url))
+	require.Equal(t, "docs ("+url+")", link(caps, "docs", url))
+
+	caps.Hyperlinks = true
+	l := link(caps, "docs", url)
+	require.Equal(t, "\x1b]8;;"+url+"\x1b\\docs\x1b]8;;\x1b\\", l)
+	require.Equal(t, len("docs"), text.RuneWidthWithoutEscSequences(l))
+
+	caps.Accessible = true
+	require.Equal(t, "docs ("+url+")", link(caps, "docs", url))
+}
+
+func TestHyperlinks(t *testing.T) {
+	clearCapabilityEnv(t)
+	require.False(t, hyperlinks())
+
+	t.Setenv("VTE_VERSION", "6003")
+	require.True(t, hyperlinks())
+
+	t.Setenv("FORCE_HYPERLINK", "0")
+	require.False(t, hyperlinks())
+
+	t.Setenv("FORCE_HYPERLINK", "1")
+	require.True(t, hyperlinks())
+
+	// Hyperlinks are only used on terminals.
+	require.False(t, DetectCapabilities(&bytes.Buffer{}).Hyperlinks)
+}
+
+func TestStructToTableLinks(t *testing.T) {
+	type site struct {
+		Name string `json:"name"`
+		URL  string `json:"url" format:"url"`
+	}
+
+	sites := []site{{Name: "a", URL: "https://a.example.com"}, {Name: "b"}}
+	link := func(text, url string) string { return "<" + url + ">" }
+
+	result, err := structToTable(sites, link)
+	require.NoError(t, err)
+	require.Equal(t, [][]string{
+		{"name", "url"},
+		{"a", "<https://a.example.com>"},
+		{"b", `""`},
+	}, result)
+
+	result, err = structToTable(sites, nil)
+	require.NoError(t, err)
+	require.Equal(t, "https://a.example.com", result[1][1])
+}
+
+func TestOpenBrowserNoninteractive(t *testing.T) {
+	clearCapabilityEnv(t)
+	t.Setenv("BROWSER", "false")
+
+	var out bytes.Buffer
+	format := Human
+	p := NewPrinter(&format)
+	p.SetHumanOutput(&out)
+	p.SetInteractive(false)
+
+	require.NoError(t, p.OpenBrowser("https://example.com"))
+	require.Equal(t, "[info] Open https://example.com in your browser\n", out.String())
+}
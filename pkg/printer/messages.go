@@ -0,0 +1,116 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package printer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// Message levels, used as the level of JSON events.
+const (
+	LevelSuccess = "success"
+	LevelInfo    = "info"
+	LevelWarning = "warning"
+	LevelError   = "error"
+	LevelStep    = "step"
+	LevelHint    = "hint"
+)
+
+// Event is the structured form of a message, printed as a line of JSON when
+// the output format is JSON.
+type Event struct {
+	Level   string `json:"level"`
+	Message string `json:"message"`
+	Step    int    `json:"step,omitempty"`
+	Total   int    `json:"total,omitempty"`
+
+	// Elapsed is the duration of a finished progress.
+	Elapsed string `json:"elapsed,omitempty"`
+}
+
+// SetEventOutput sets the output of the JSON events printed by the message
+// methods, which defaults to os.Stderr so that they don't mix with the
+// resources printed to stdout.
+func (p *Printer) SetEventOutput(out io.Writer) {
+	p.eventOut = out
+}
+
+// Success prints a message about an operation that succeeded.
+func (p *Printer) Success(format string, a ...interface{}) {
+	p.message(Event{Level: LevelSuccess, Message: fmt.Sprintf(format, a...)}, p.Symbols().Success, p.Theme().Success, nil)
+}
+
+// Info prints an informational message.
+func (p *Printer) Info(format string, a ...interface{}) {
+	p.message(Event{Level: LevelInfo, Message: fmt.Sprintf(format, a...)}, p.Symbols().Info, p.Theme().Accent, nil)
+}
+
+// Warning prints a message about a problem that didn't stop the command.
+func (p *Printer) Warning(format string, a ...interface{}) {
+	p.message(Event{Level: LevelWarning, Message: fmt.Sprintf(format, a...)}, p.Symbols().Warning, p.Theme().Warning, nil)
+}
+
+// Failure prints a message about an operation that failed.
+func (p *Printer) Failure(format string, a ...interface{}) {
+	p.message(Event{Level: LevelError, Message: fmt.Sprintf(format, a...)}, p.Symbols().Error, p.Theme().Error, nil)
+}
+
+// Step prints a message about step n of a total number of steps.
+func (p *Printer) Step(n, total int, format string, a ...interface{}) {
+	e := Event{
+		Level:   LevelStep,
+		Message: fmt.Sprintf(format, a...),
+		Step:    n,
+		Total:   total,
+	}
+	p.message(e, fmt.Sprintf("[%d/%d]", n, total), p.Theme().Accent, nil)
+}
+
+// Hint prints a muted message suggesting what to do next, such as the
+// command to run.
+func (p *Printer) Hint(format string, a ...interface{}) {
+	p.message(Event{Level: LevelHint, Message: fmt.Sprintf(format, a...)}, "", nil, p.Theme().Muted)
+}
+
+// message prints e in human-readable form, prefixed by the given symbol, or
+// as a JSON event if human-readable output is discarded. Other formats don't
+// print messages.
+func (p *Printer) message(e Event, symbol string, symbolStyle, textStyle Style) {
+	if out := p.out(); out != io.Discard {
+		var line string
+		if symbol != "" {
+			// Indent continuation lines so they line up with the first one.
+			indent := "\n" + strings.Repeat(" ", utf8.RuneCountInString(symbol)+1)
+			line = symbolStyle.Sprint(symbol) + " " + textStyle.Sprint(strings.ReplaceAll(e.Message, "\n", indent))
+		} else {
+			line = textStyle.Sprint(e.Message)
+		}
+
+		if e.Elapsed != "" {
+			line += " " + p.Theme().Muted.Sprintf("(%s)", e.Elapsed)
+		}
+
+		_ = p.write(out, []byte(line+"\n"))
+		return
+	}
+
+	if p.quiet || *p.format != JSON {
+		return
+	}
+
+	b, err := json.Marshal(e)
+	if err != nil {
+		return
+	}
+
+	out := p.eventOut
+	if out == nil {
+		out = os.Stderr
+	}
+	_ = p.write(out, append(b, '\n'))
+}
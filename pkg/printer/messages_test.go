@@ -0,0 +1,75 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package printer
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMessagesHuman(t *testing.T) {
+	clearCapabilityEnv(t)
+
+	var out bytes.Buffer
+	format := Human
+	p := NewPrinter(&format)
+	p.SetHumanOutput(&out)
+
+	p.Success("created %s", "a")
+	p.Info("info")
+	p.Warning("first\nsecond")
+	p.Failure("failed")
+	p.Step(1, 3, "building")
+	p.Hint("run %q", "myapp get a")
+
+	require.Equal(t, strings.Join([]string{
+		"[ok] created a",
+		"[info] info",
+		"[warn] first",
+		"       second",
+		"[error] failed",
+		"[1/3] building",
+		`run "myapp get a"`,
+		"",
+	}, "\n"), out.String())
+
+	out.Reset()
+	p.SetAccessible(true)
+	p.Success("done")
+	require.Equal(t, "Success: done\n", out.String())
+
+	out.Reset()
+	p.SetQuiet(true)
+	p.Failure("failed")
+	require.Empty(t, out.String())
+}
+
+func TestMessagesJSON(t *testing.T) {
+	var events bytes.Buffer
+	format := JSON
+	p := NewPrinter(&format)
+	p.SetEventOutput(&events)
+
+	p.Success("created")
+	p.Step(2, 3, "uploading")
+
+	var got []Event
+	for _, line := range strings.Split(strings.TrimSpace(events.String()), "\n") {
+		var e Event
+		require.NoError(t, json.Unmarshal([]byte(line), &e))
+		got = append(got, e)
+	}
+	require.Equal(t, []Event{
+		{Level: LevelSuccess, Message: "created"},
+		{Level: LevelStep, Message: "uploading", Step: 2, Total: 3},
+	}, got)
+
+	events.Reset()
+	format = YAML
+	p.Info("info")
+	require.Empty(t, events.String())
+}
@@ -4,21 +4,23 @@ package printer
 
 import (
 	"bytes"
+	"context"
+	"encoding/csv"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"reflect"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/AlecAivazis/survey/v2"
-	"github.com/AlecAivazis/survey/v2/terminal"
 	"github.com/briandowns/spinner"
 	"github.com/fatih/color"
 	"github.com/jedib0t/go-pretty/v6/table"
-	"github.com/mattn/go-isatty"
 	"gopkg.in/yaml.v3"
 )
 
@@ -26,10 +28,80 @@ var (
 	errInputNotASliceOfStructs = errors.New("input is not a slice of structs")
 	errInputNotASlice          = errors.New("input is not a slice")
 	errElementNotASlice        = errors.New("element is not a slice")
+	errNoIdentifier            = errors.New("resource has no identifier field")
 )
 
-// structToTable converts a slice of structs into a tabular representation
-func structToTable(data interface{}) ([][]string, error) {
+// tableOptions returns the options of the table struct tag of the given
+// field, such as "key" in `table:",key"`.
+func tableOptions(field reflect.StructField) []string {
+	_, opts, _ := strings.Cut(field.Tag.Get("table"), ",")
+	if opts == "" {
+		return nil
+	}
+	return strings.Split(opts, ",")
+}
+
+// identifierField returns the index of the field of the struct type t that
+// identifies it. This is the field tagged with the table "key" option or,
+// if there is none, the field named "id".
+func identifierField(t reflect.Type) (int, error) {
+	for i := 0; i < t.NumField(); i++ {
+		for _, opt := range tableOptions(t.Field(i)) {
+			if opt == "key" {
+				return i, nil
+			}
+		}
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		if strings.EqualFold(t.Field(i).Name, "id") {
+			return i, nil
+		}
+	}
+
+	return 0, errNoIdentifier
+}
+
+// structToIdentifiers returns the identifier of a struct or of each struct in
+// a slice of structs.
+func structToIdentifiers(data interface{}) ([]string, error) {
+	val := reflect.Indirect(reflect.ValueOf(data))
+
+	var elems []reflect.Value
+	switch val.Kind() {
+	case reflect.Struct:
+		elems = append(elems, val)
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < val.Len(); i++ {
+			elems = append(elems, reflect.Indirect(val.Index(i)))
+		}
+	default:
+		return nil, errNoIdentifier
+	}
+
+	var ids []string
+	for _, elem := range elems {
+		if elem.Kind() != reflect.Struct {
+			return nil, errNoIdentifier
+		}
+
+		idx, err := identifierField(elem.Type())
+		if err != nil {
+			return nil, err
+		}
+
+		// Identifier fields may be unexported, which Interface doesn't
+		// support.
+		ids = append(ids, fmt.Sprint(elem.Field(idx)))
+	}
+
+	return ids, nil
+}
+
+// structToTable converts a slice of structs into a tabular representation.
+// If link is not nil, it formats the values of fields tagged with
+// `format:"url"`.
+func structToTable(data interface{}, link func(text, url string) string) ([][]string, error) {
 	val := reflect.ValueOf(data)
 
 	if val.Kind() != reflect.Slice {
@@ -43,8 +115,15 @@ func structToTable(data interface{}) ([][]string, error) {
 		return nil, errors.Join(errInputNotASliceOfStructs, errElementNotASlice)
 	}
 
+	// Unexported fields can't be printed.
+	var fields []int
 	for i := 0; i < elemType.NumField(); i++ {
 		field := elemType.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		fields = append(fields, i)
+
 		fieldName := field.Tag.Get("json")
 		if fieldName == "" {
 			fieldName = field.Name
@@ -61,8 +140,8 @@ func structToTable(data interface{}) ([][]string, error) {
 
 	for i := 0; i < val.Len(); i++ {
 		elem := val.Index(i)
-		values := make([]string, elem.NumField())
-		for j := 0; j < elem.NumField(); j++ {
+		values := make([]string, len(fields))
+		for k, j := range fields {
 			fieldValue := elem.Field(j).Interface()
 			fieldValueStr, err := yaml.Marshal(fieldValue)
 			if err != nil {
@@ -70,7 +149,11 @@ func structToTable(data interface{}) ([][]string, error) {
 			}
 
 			// Remove trailing newline from YAML output since we add it ourselves when printing
-			values[j] = strings.TrimSuffix(string(fieldValueStr), "\n")
+			values[k] = strings.TrimSuffix(string(fieldValueStr), "\n")
+
+			if link != nil && elemType.Field(j).Tag.Get("format") == "url" && !elem.Field(j).IsZero() {
+				values[k] = link(values[k], fmt.Sprint(fieldValue))
+			}
 		}
 		result = append(result, values)
 	}
@@ -78,7 +161,11 @@ func structToTable(data interface{}) ([][]string, error) {
 	return result, nil
 }
 
-var IsTTY = isatty.IsTerminal(os.Stdout.Fd()) || isatty.IsCygwinTerminal(os.Stdout.Fd())
+// IsTTY is true if stdout is a terminal.
+//
+// Deprecated: Use Printer.Capabilities, or DetectCapabilities to probe other
+// outputs.
+var IsTTY = DetectCapabilities(os.Stdout).TTY
 
 // Format defines the option output format of a resource.
 type Format int
@@ -88,8 +175,25 @@ const (
 	// a single line, depending on the resource implementation.
 	Human Format = iota
 	JSON
+	YAML
+	CSV
 )
 
+// FormatFromPath returns the format matching the extension of the given file
+// path, if there is one.
+func FormatFromPath(path string) (Format, bool) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		return JSON, true
+	case ".yaml", ".yml":
+		return YAML, true
+	case ".csv":
+		return CSV, true
+	}
+
+	return Human, false
+}
+
 // NewFormatValue is used to define a flag that can be used to define a custom
 // flag via the flagset.Var() method.
 func NewFormatValue(val Format, p *Format) *Format {
@@ -103,6 +207,10 @@ func (f *Format) String() string {
 		return "human"
 	case JSON:
 		return "json"
+	case YAML:
+		return "yaml"
+	case CSV:
+		return "csv"
 	}
 
 	return "unknown format"
@@ -115,9 +223,13 @@ func (f *Format) Set(s string) error {
 		v = Human
 	case "json":
 		v = JSON
+	case "yaml":
+		v = YAML
+	case "csv":
+		v = CSV
 	default:
 		return fmt.Errorf("failed to parse Format: %q. Valid values: %+v",
-			s, []string{"human", "json"})
+			s, []string{"human", "json", "yaml", "csv"})
 	}
 
 	*f = v
@@ -132,8 +244,37 @@ func (f *Format) Type() string {
 type Printer struct {
 	humanOut    io.Writer
 	resourceOut io.Writer
-
-	format *Format
+	eventOut    io.Writer
+
+	ctx            context.Context
+	format         *Format
+	resourceFormat *Format
+	quiet          bool
+	noninteractive bool
+	promptTimeout  time.Duration
+
+	assumeYes     bool
+	assumeYesFlag string
+	assumeYesEnv  string
+
+	theme      *Theme
+	accessible bool
+	noColor    bool
+
+	// capsMu guards the capabilities of the outputs, which are probed once.
+	capsMu       sync.Mutex
+	humanCaps    *Capabilities
+	resourceCaps *Capabilities
+
+	// mu serialises all writes of the printer, including the frames of the
+	// active spinner.
+	mu      sync.Mutex
+	spinner *spinner.Spinner
+
+	// lineWriter is used by Printf, Println and Print, and partial holds the
+	// line writers with a partial line.
+	lineWriter *lineWriter
+	partial    []*lineWriter
 }
 
 // NewPrinter returns a new Printer for the given output and format.
@@ -145,23 +286,38 @@ func NewPrinter(format *Format) *Printer {
 
 // Printf is a convenience method to Printf to the defined output.
 func (p *Printer) Printf(format string, i ...interface{}) {
-	_, _ = fmt.Fprintf(p.Out(), format, i...)
+	_, _ = fmt.Fprintf(p.lines(), format, i...)
 }
 
 // Println is a convenience method to Println to the defined output.
 func (p *Printer) Println(i ...interface{}) {
-	_, _ = fmt.Fprintln(p.Out(), i...)
+	_, _ = fmt.Fprintln(p.lines(), i...)
 }
 
 // Print is a convenience method to Print to the defined output.
 func (p *Printer) Print(i ...interface{}) {
-	_, _ = fmt.Fprint(p.Out(), i...)
+	_, _ = fmt.Fprint(p.lines(), i...)
 }
 
 // Out defines the output to write human-readable text. If format is not set to
 // human, Out returns io.Discard, which means that any output will be
-// discarded
+// discarded. Writes to Out are line-atomic, so it is safe to print from
+// multiple goroutines. Every writer returned by Out keeps its own partial
+// line, which is written once it is completed or flushed.
 func (p *Printer) Out() io.Writer {
+	out := p.out()
+	if out == io.Discard {
+		return out
+	}
+
+	return &lineWriter{p: p, out: out}
+}
+
+func (p *Printer) out() io.Writer {
+	if p.quiet {
+		return io.Discard
+	}
+
 	if p.humanOut != nil {
 		return p.humanOut
 	}
@@ -173,41 +329,71 @@ func (p *Printer) Out() io.Writer {
 	return io.Discard
 }
 
-// PrintProgress starts a spinner with the relevant message. The returned
-// function needs to be called in a defer or when it's decided to stop the
-// spinner
-func (p *Printer) PrintProgress(message string) func() {
-	if !IsTTY {
-		_, _ = fmt.Fprintln(p.Out(), message)
-		return func() {}
+// SetContext sets the context used by the methods that don't take one, such
+// as PrintProgress and ConfirmCommand, usually the context of the command.
+func (p *Printer) SetContext(ctx context.Context) {
+	p.ctx = ctx
+}
+
+func (p *Printer) context() context.Context {
+	if p.ctx == nil {
+		return context.Background()
 	}
+	return p.ctx
+}
 
-	s := spinner.New(spinner.CharSets[14], 100*time.Millisecond, spinner.WithWriter(p.Out()))
-	s.Suffix = fmt.Sprintf(" %s", message)
+// SetFormat sets the output format, replacing the one passed to NewPrinter.
+func (p *Printer) SetFormat(format Format) {
+	p.format = &format
+}
 
-	_ = s.Color("bold", "magenta")
-	s.Start()
-	return func() {
-		s.Stop()
+// Format returns the format that was set for this printer
+func (p *Printer) Format() Format { return *p.format }
 
-		// NOTE(fatih) the spinner library doesn't clear the line properly,
-		// hence remove it ourselves. This line should be removed once it's
-		// fixed in upstream.  https://github.com/briandowns/spinner/pull/117
-		_, _ = fmt.Fprint(p.Out(), "\r\033[2K")
+// SetResourceFormat sets the format of resources printed by PrintResource,
+// when it differs from the format of the printer, such as the format
+// inferred from the extension of an output file.
+func (p *Printer) SetResourceFormat(format Format) {
+	p.resourceFormat = &format
+}
+
+// ResourceFormat returns the format of resources printed by PrintResource.
+func (p *Printer) ResourceFormat() Format {
+	if p.resourceFormat != nil {
+		return *p.resourceFormat
 	}
+	return *p.format
 }
 
-// Format returns the format that was set for this printer
-func (p *Printer) Format() Format { return *p.format }
+// SetQuiet enables or disables quiet mode. In quiet mode human-readable
+// messages are discarded and PrintResource only prints identifiers.
+func (p *Printer) SetQuiet(quiet bool) {
+	p.quiet = quiet
+	p.resetCapabilities()
+}
+
+// Quiet returns whether quiet mode is enabled for this printer
+func (p *Printer) Quiet() bool { return p.quiet }
 
 // SetHumanOutput sets the output for human readable messages.
 func (p *Printer) SetHumanOutput(out io.Writer) {
 	p.humanOut = out
+	p.resetCapabilities()
 }
 
 // SetResourceOutput sets the output for printing resources via PrintResource.
 func (p *Printer) SetResourceOutput(out io.Writer) {
 	p.resourceOut = out
+	p.resetCapabilities()
+}
+
+// resourceOutput returns the output for printing resources.
+func (p *Printer) resourceOutput() io.Writer {
+	if p.resourceOut != nil {
+		return p.resourceOut
+	}
+
+	return os.Stdout
 }
 
 // PrintResource prints the given resource in the format it was specified.
@@ -216,20 +402,32 @@ func (p *Printer) PrintResource(v interface{}) error {
 		return errors.New("printer.Format is not set")
 	}
 
-	var out io.Writer = os.Stdout
-	if p.resourceOut != nil {
-		out = p.resourceOut
-	}
+	out := p.resourceOutput()
 
-	switch *p.format {
+	switch p.ResourceFormat() {
 	case Human:
+		if p.quiet {
+			ids, err := structToIdentifiers(v)
+			if err != nil {
+				return err
+			}
+
+			for _, id := range ids {
+				_ = p.write(out, []byte(id+"\n"))
+			}
+			return nil
+		}
+
 		var b string
-		result, err := structToTable(v)
+		// Links and colours are only emitted if the resource output
+		// supports them.
+		caps := p.resourceCapabilities()
+		result, err := structToTable(v, func(text, url string) string {
+			return link(caps, text, url)
+		})
 		if err == nil {
 			t := table.NewWriter()
-			if !color.NoColor {
-				t.SetStyle(table.StyleColoredBlackOnMagentaWhite)
-			}
+			t.SetStyle(p.tableStyle(caps))
 
 			for i, line := range result {
 				row := make(table.Row, len(line))
@@ -253,42 +451,58 @@ func (p *Printer) PrintResource(v interface{}) error {
 
 			// Remove trailing newline from YAML output since we add it ourselves when printing
 			b = strings.TrimSuffix(string(s), "\n")
+			if shouldHighlight(caps) {
+				b = highlightYAML(b, p.themeFor(caps))
+			}
 		} else {
 			return err
 		}
 
-		_, _ = fmt.Fprintln(out, b)
-		return nil
+		return p.write(out, []byte(b+"\n"))
 	case JSON:
 		return p.PrintJSON(v)
+	case YAML:
+		return p.PrintYAML(v)
+	case CSV:
+		return p.PrintCSV(v)
 	}
 
 	return fmt.Errorf("unknown printer.Format: %T", *p.format)
 }
 
+// ConfirmCommand asks the user to type confirmationName to confirm running
+// the command. It is equivalent to ConfirmCommandContext with the context
+// set with SetContext.
 func (p *Printer) ConfirmCommand(confirmationName, commandShortName, confirmFailedName string) error {
+	return p.ConfirmCommandContext(p.context(), confirmationName, commandShortName, confirmFailedName)
+}
+
+// ConfirmCommandContext asks the user to type confirmationName to confirm
+// running the command. The prompt is aborted with an error matching
+// context.Canceled when the user interrupts it or ctx is done.
+func (p *Printer) ConfirmCommandContext(ctx context.Context, confirmationName, commandShortName, confirmFailedName string) error {
+	if p.assumeYes {
+		return nil
+	}
+
 	if p.Format() != Human {
-		return fmt.Errorf("cannot %s with the output format %q (run with -force to override)", commandShortName, p.Format())
+		return fmt.Errorf("cannot %s with the output format %q%s", commandShortName, p.format, p.assumeYesHint())
 	}
 
-	if !IsTTY {
-		return fmt.Errorf("cannot confirm %s %q (run with -force to override)", confirmFailedName, confirmationName)
+	if p.noninteractive || !isPromptTTY() {
+		return fmt.Errorf("cannot confirm %s %q%s", confirmFailedName, confirmationName, p.assumeYesHint())
 	}
 
-	confirmationMessage := fmt.Sprintf("%s %s %s", Bold("Please type"), BoldBlue(confirmationName), Bold("to confirm:"))
+	// Survey already styles the message, only the name uses the theme.
+	confirmationMessage := fmt.Sprintf("Please type %s to confirm:", p.Theme().Accent.Sprint(confirmationName))
 
 	prompt := &survey.Input{
 		Message: confirmationMessage,
 	}
 
 	var userInput string
-	err := survey.AskOne(prompt, &userInput)
-	if err != nil {
-		if err == terminal.InterruptErr {
-			os.Exit(0)
-		} else {
-			return err
-		}
+	if err := p.ask(ctx, "", prompt, &userInput); err != nil {
+		return err
 	}
 
 	// If the confirmations don't match up, let's return an error.
@@ -300,35 +514,77 @@ func (p *Printer) ConfirmCommand(confirmationName, commandShortName, confirmFail
 }
 
 func (p *Printer) PrintJSON(v interface{}) error {
-	var out io.Writer = os.Stdout
-	if p.resourceOut != nil {
-		out = p.resourceOut
-	}
+	out := p.resourceOutput()
 
 	buf, err := json.MarshalIndent(v, "", "  ")
 	if err != nil {
 		return err
 	}
 
-	_, _ = fmt.Fprintln(out, string(buf))
-	return nil
+	if caps := p.resourceCapabilities(); shouldHighlight(caps) {
+		buf = highlightJSON(buf, p.themeFor(caps))
+	}
 
+	return p.write(out, append(buf, '\n'))
 }
 
-func (p *Printer) PrettyPrintJSON(b []byte) error {
-	var out io.Writer = os.Stdout
-	if p.resourceOut != nil {
-		out = p.resourceOut
+func (p *Printer) PrintYAML(v interface{}) error {
+	out := p.resourceOutput()
+
+	buf, err := yaml.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	b := string(buf)
+	if caps := p.resourceCapabilities(); shouldHighlight(caps) {
+		b = highlightYAML(b, p.themeFor(caps))
+	}
+
+	return p.write(out, []byte(b))
+}
+
+// PrintCSV prints a struct or a slice of structs as CSV, with one record per
+// struct preceded by a header record.
+func (p *Printer) PrintCSV(v interface{}) error {
+	out := p.resourceOutput()
+
+	// A single struct is printed as a table with one row.
+	val := reflect.Indirect(reflect.ValueOf(v))
+	if val.Kind() == reflect.Struct {
+		s := reflect.MakeSlice(reflect.SliceOf(val.Type()), 1, 1)
+		s.Index(0).Set(val)
+		v = s.Interface()
+	}
+
+	result, err := structToTable(v, nil)
+	if err != nil {
+		return err
 	}
 
+	var buf bytes.Buffer
+	if err := csv.NewWriter(&buf).WriteAll(result); err != nil {
+		return err
+	}
+
+	return p.write(out, buf.Bytes())
+}
+
+func (p *Printer) PrettyPrintJSON(b []byte) error {
+	out := p.resourceOutput()
+
 	var buf bytes.Buffer
 	err := json.Indent(&buf, b, "", "  ")
 	if err != nil {
 		return err
 	}
 
-	_, _ = fmt.Fprintln(out, buf.String())
-	return nil
+	if caps := p.resourceCapabilities(); shouldHighlight(caps) {
+		return p.write(out, append(highlightJSON(buf.Bytes(), p.themeFor(caps)), '\n'))
+	}
+
+	buf.WriteByte('\n')
+	return p.write(out, buf.Bytes())
 }
 
 func GetMilliseconds(timestamp time.Time) int64 {
@@ -352,46 +608,65 @@ func GetMillisecondsIfExists(timestamp *time.Time) *int64 {
 	return &numSeconds
 }
 
+// Emoji returns the given emoji if stdout is a terminal that can display it,
+// and an empty string otherwise.
+//
+// Deprecated: Use Printer.Emoji, which probes the output the printer writes
+// to.
 func Emoji(emoji string) string {
-	if IsTTY {
+	if caps := DetectCapabilities(os.Stdout); caps.TTY && caps.Unicode {
 		return emoji
 	}
 	return ""
 }
 
 // BoldBlue returns a string formatted with blue and bold.
+//
+// Deprecated: Use the roles of Printer.Theme, such as Theme().Accent.Sprint,
+// which follow the theme selected with --theme.
 func BoldBlue(msg interface{}) string {
-	// the 'color' package already handles IsTTY gracefully
 	return color.New(color.FgBlue).Add(color.Bold).Sprint(msg)
 }
 
 // BoldRed returns a string formatted with red and bold.
+//
+// Deprecated: Use Printer.Theme().Error.Sprint.
 func BoldRed(msg interface{}) string {
 	return color.New(color.FgRed).Add(color.Bold).Sprint(msg)
 }
 
 // BoldGreen returns a string formatted with green and bold.
+//
+// Deprecated: Use Printer.Theme().Success.Sprint.
 func BoldGreen(msg interface{}) string {
 	return color.New(color.FgGreen).Add(color.Bold).Sprint(msg)
 }
 
 // BoldBlack returns a string formatted with Black and bold.
+//
+// Deprecated: Use the roles of Printer.Theme, which are readable on both
+// dark and light terminals.
 func BoldBlack(msg interface{}) string {
 	return color.New(color.FgBlack).Add(color.Bold).Sprint(msg)
 }
 
 // BoldYellow returns a string formatted with yellow and bold.
+//
+// Deprecated: Use Printer.Theme().Warning.Sprint.
 func BoldYellow(msg interface{}) string {
 	return color.New(color.FgYellow).Add(color.Bold).Sprint(msg)
 }
 
-// Red returns a string formatted with red and bold.
+// Red returns a string formatted with red.
+//
+// Deprecated: Use Printer.Theme().Error.Sprint.
 func Red(msg interface{}) string {
 	return color.New(color.FgRed).Sprint(msg)
 }
 
 // Bold returns a string formatted with bold.
+//
+// Deprecated: Use the roles of Printer.Theme, such as Theme().Accent.Sprint.
 func Bold(msg interface{}) string {
-	// the 'color' package already handles IsTTY gracefully
 	return color.New(color.Bold).Sprint(msg)
 }
@@ -0,0 +1,247 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package printer
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testResource struct {
+	Name string `json:"name" table:",key"`
+	ID   string `json:"id"`
+	Size int    `json:"size"`
+}
+
+func TestPrintResourceQuiet(t *testing.T) {
+	testCases := []struct {
+		name        string
+		resource    interface{}
+		expected    string
+		expectError bool
+	}{
+		{
+			name: "slice of structs",
+			resource: []testResource{
+				{Name: "a", ID: "1"},
+				{Name: "b", ID: "2"},
+			},
+			expected: "a\nb\n",
+		},
+		{
+			name:     "pointer to struct",
+			resource: &testResource{Name: "a", ID: "1"},
+			expected: "a\n",
+		},
+		{
+			name: "id field",
+			resource: []struct {
+				ID   int
+				Name string
+			}{{ID: 1}, {ID: 2}},
+			expected: "1\n2\n",
+		},
+		{
+			name: "unexported key",
+			resource: []struct {
+				name string `table:",key"`
+			}{{name: "a"}},
+			expected: "a\n",
+		},
+		{
+			name:        "no identifier",
+			resource:    []struct{ Name string }{{Name: "a"}},
+			expectError: true,
+		},
+		{
+			name:        "map",
+			resource:    map[string]string{"id": "1"},
+			expectError: true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var out, human bytes.Buffer
+
+			f := Human
+			p := NewPrinter(&f)
+			p.SetQuiet(true)
+			p.SetResourceOutput(&out)
+			p.SetHumanOutput(&human)
+
+			p.Printf("should be discarded")
+
+			err := p.PrintResource(tc.resource)
+			if tc.expectError {
+				require.ErrorIs(t, err, errNoIdentifier)
+				return
+			}
+
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, out.String())
+			require.Empty(t, human.String())
+		})
+	}
+}
+
+func TestStructToTableUnexported(t *testing.T) {
+	type resource struct {
+		Name  string `json:"name" table:",key"`
+		size  int
+		Ready bool
+	}
+
+	result, err := structToTable([]resource{{Name: "a", size: 1, Ready: true}}, nil)
+	require.NoError(t, err)
+	require.Equal(t, [][]string{{"name", "Ready"}, {"a", "true"}}, result)
+}
+
+func TestPrinterConcurrentWrites(t *testing.T) {
+	var out bytes.Buffer
+
+	f := Human
+	p := NewPrinter(&f)
+	p.SetHumanOutput(&out)
+
+	const goroutines = 8
+	const lines = 100
+
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < lines; i += 2 {
+				p.Printf("goroutine %d line %d\ngoroutine %d line %d\n", g, i, g, i+1)
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	got := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	require.Len(t, got, goroutines*lines)
+	for _, line := range got {
+		require.Regexp(t, `^goroutine \d+ line \d+$`, line)
+	}
+
+	p.Print("partial")
+	require.NotContains(t, out.String(), "partial")
+	p.Println(" line")
+	require.True(t, strings.HasSuffix(out.String(), "\npartial line\n"))
+
+	p.Print("partial")
+	p.Flush()
+	require.True(t, strings.HasSuffix(out.String(), "\npartial"))
+}
+
+func TestPrinterPartialLines(t *testing.T) {
+	clearCapabilityEnv(t)
+
+	var out bytes.Buffer
+
+	f := Human
+	p := NewPrinter(&f)
+	p.SetHumanOutput(&out)
+
+	a, b := p.Out(), p.Out()
+	_, _ = a.Write([]byte("a1 "))
+	_, _ = b.Write([]byte("b1 "))
+	_, _ = b.Write([]byte("b2\n"))
+	_, _ = a.Write([]byte("a2\n"))
+	require.Equal(t, "b1 b2\na1 a2\n", out.String())
+
+	// Direct writes flush partial lines first, to keep the order.
+	out.Reset()
+	p.Print("Deploying... ")
+	p.Success("Deployed")
+	require.Equal(t, "Deploying... [ok] Deployed\n", out.String())
+}
+
+func TestPromptUnavailable(t *testing.T) {
+	testCases := []struct {
+		name        string
+		format      Format
+		interactive bool
+		reason      string
+	}{
+		{
+			name:   "noninteractive",
+			format: Human,
+			reason: "in non-interactive mode",
+		},
+		{
+			name:        "json",
+			format:      JSON,
+			interactive: true,
+			reason:      `with the output format "json"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := tc.format
+			p := NewPrinter(&f)
+			p.SetInteractive(tc.interactive)
+
+			_, err := p.Input(context.Background(), "name", "Name", "")
+			require.ErrorIs(t, err, ErrCannotPrompt)
+			require.EqualError(t, err, "cannot prompt for input "+tc.reason+" (run with --name to provide a value)")
+
+			_, err = p.Select(context.Background(), "region", "Region", []string{"a", "b"}, "")
+			require.ErrorIs(t, err, ErrCannotPrompt)
+
+			var promptErr *PromptError
+			_, err = p.Confirm(context.Background(), "", "Continue?", false)
+			require.ErrorAs(t, err, &promptErr)
+			require.Empty(t, promptErr.Flag)
+			require.False(t, p.Interactive())
+		})
+	}
+}
+
+func TestContextReader(t *testing.T) {
+	r, w, err := os.Pipe()
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		_ = r.Close()
+		_ = w.Close()
+	})
+
+	in := newFileReader(r)
+	ctx, cancel := context.WithCancel(context.Background())
+	cr := &contextReader{ctx: ctx, in: in}
+
+	_, err = w.Write([]byte("y"))
+	require.NoError(t, err)
+
+	b := make([]byte, 8)
+	n, err := cr.Read(b)
+	require.NoError(t, err)
+	require.Equal(t, "y", string(b[:n]))
+
+	cancel()
+	_, err = cr.Read(b)
+	require.ErrorIs(t, err, context.Canceled)
+
+	// Input that arrives after a read was canceled goes to the next reader.
+	_, err = w.Write([]byte("n"))
+	require.NoError(t, err)
+
+	cr = &contextReader{ctx: context.Background(), in: in}
+	n, err = cr.Read(b)
+	require.NoError(t, err)
+	require.Equal(t, "n", string(b[:n]))
+}
+
+func TestPromptTimeoutCanceled(t *testing.T) {
+	err := &canceledError{msg: "prompt timed out after 1s", err: context.Canceled, cause: context.DeadlineExceeded}
+	require.ErrorIs(t, err, context.Canceled)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+}
@@ -0,0 +1,640 @@
+// SPDX-License-Identifier: Apache-2.0
+
+// Package printertest provides a fake printer.Interface for unit testing
+// commands.
+package printertest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/loopholelabs/cmdutils/pkg/printer"
+)
+
+// Resource is a value printed by one of the resource methods.
+type Resource struct {
+	// Method is the name of the method that printed the value, such as
+	// "PrintResource" or "PrintJSON".
+	Method string
+	Value  interface{}
+}
+
+// Message is a message printed by one of the message methods.
+type Message struct {
+	// Level is one of the printer.Level constants.
+	Level string
+	Text  string
+
+	// Step and Total are set for printer.LevelStep messages.
+	Step  int
+	Total int
+}
+
+// Prompt is a prompt that was shown to the user.
+type Prompt struct {
+	// Method is the name of the prompt method, such as "Input" or
+	// "ConfirmCommand".
+	Method  string
+	Flag    string
+	Message string
+	Options []string
+	Default interface{}
+}
+
+// ProgressEvent is a change of the state of a progress started with
+// PrintProgress.
+type ProgressEvent struct {
+	// Progress is the index of the progress, in the order in which they
+	// were started.
+	Progress int
+
+	// Event is "start", "update", "stop" or, for results, one of the
+	// printer.Level constants.
+	Event   string
+	Message string
+}
+
+// Recorder is a printer.Interface that records every call for assertions
+// and answers prompts with scripted answers. It is safe for concurrent use.
+type Recorder struct {
+	mu sync.Mutex
+
+	format         printer.Format
+	resourceFormat *printer.Format
+	quiet          bool
+	noninteractive bool
+	assumeYes      bool
+	theme          *printer.Theme
+	humanOut       io.Writer
+
+	out       bytes.Buffer
+	resources []Resource
+	messages  []Message
+	prompts   []Prompt
+	progress  []ProgressEvent
+	urls      []string
+	answers   []interface{}
+	started   int
+}
+
+var _ printer.Interface = (*Recorder)(nil)
+
+// NewRecorder returns a Recorder for the human-readable format that can
+// prompt the user.
+func NewRecorder() *Recorder {
+	return &Recorder{}
+}
+
+// SetFormat sets the format returned by Format.
+func (r *Recorder) SetFormat(format printer.Format) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.format = format
+}
+
+// SetResourceFormat sets the format returned by ResourceFormat, which
+// defaults to the format set by SetFormat.
+func (r *Recorder) SetResourceFormat(format printer.Format) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.resourceFormat = &format
+}
+
+// SetQuiet sets the value returned by Quiet.
+func (r *Recorder) SetQuiet(quiet bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.quiet = quiet
+}
+
+// SetInteractive sets whether prompts are answered. Prompts of a
+// non-interactive Recorder fail with a *printer.PromptError.
+func (r *Recorder) SetInteractive(interactive bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.noninteractive = !interactive
+}
+
+// SetAssumeYes makes prompts behave like a Printer with SetAssumeYes
+// enabled, answering confirmations and accepting defaults without using the
+// scripted answers.
+func (r *Recorder) SetAssumeYes(assumeYes bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.assumeYes = assumeYes
+}
+
+// SetAssumeYesHint does nothing, errors of unconfirmed commands have no
+// hint.
+func (r *Recorder) SetAssumeYesHint(string, string) {}
+
+// SetPromptTimeout does nothing, prompts are answered immediately.
+func (r *Recorder) SetPromptTimeout(time.Duration) {}
+
+// SetNoColor does nothing, recorded output never has colours.
+func (r *Recorder) SetNoColor(bool) {}
+
+// SetAccessible does nothing, progress is recorded as events.
+func (r *Recorder) SetAccessible(bool) {}
+
+// SetTheme sets the theme returned by Theme.
+func (r *Recorder) SetTheme(theme *printer.Theme) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.theme = theme
+}
+
+// SetContext does nothing, progress isn't stopped by a context.
+func (r *Recorder) SetContext(context.Context) {}
+
+// SetHumanOutput makes output written to Out also be written to out, in
+// addition to being recorded.
+func (r *Recorder) SetHumanOutput(out io.Writer) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.humanOut = out
+}
+
+// SetResourceOutput does nothing, resources are recorded as values.
+func (r *Recorder) SetResourceOutput(io.Writer) {}
+
+// SetEventOutput does nothing, messages are recorded as values.
+func (r *Recorder) SetEventOutput(io.Writer) {}
+
+// Answer appends scripted answers, which are used by the prompts in order.
+// An answer is a string for Input, Password, Select and ConfirmCommand, a
+// []string for MultiSelect and a bool for Confirm and ConfirmCommand. An
+// error answer is returned by the prompt instead.
+func (r *Recorder) Answer(answers ...interface{}) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.answers = append(r.answers, answers...)
+}
+
+// Output returns the human-readable text written with Out, Printf, Println
+// and Print.
+func (r *Recorder) Output() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.out.String()
+}
+
+// Resources returns the printed resources.
+func (r *Recorder) Resources() []Resource {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.resources)
+}
+
+// Messages returns the printed messages.
+func (r *Recorder) Messages() []Message {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.messages)
+}
+
+// Prompts returns the prompts, including those answered automatically.
+func (r *Recorder) Prompts() []Prompt {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.prompts)
+}
+
+// ProgressEvents returns the events of all progress started with
+// PrintProgress.
+func (r *Recorder) ProgressEvents() []ProgressEvent {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.progress)
+}
+
+// OpenedURLs returns the URLs passed to OpenBrowser.
+func (r *Recorder) OpenedURLs() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return slices.Clone(r.urls)
+}
+
+func (r *Recorder) Format() printer.Format {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.format
+}
+
+func (r *Recorder) ResourceFormat() printer.Format {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.resourceFormat != nil {
+		return *r.resourceFormat
+	}
+	return r.format
+}
+
+func (r *Recorder) Quiet() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.quiet
+}
+
+// Interactive reports whether prompts are answered, which like for a Printer
+// requires the human-readable format.
+func (r *Recorder) Interactive() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return !r.noninteractive && r.format == printer.Human
+}
+
+func (r *Recorder) AssumeYes() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.assumeYes
+}
+
+// Theme returns the theme set with SetTheme, or printer.MonoTheme so that
+// recorded output has no colours.
+func (r *Recorder) Theme() *printer.Theme {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.theme != nil {
+		return r.theme
+	}
+	return printer.MonoTheme
+}
+
+// Capabilities returns the capabilities of a plain, non-terminal output.
+func (r *Recorder) Capabilities() printer.Capabilities { return printer.Capabilities{} }
+
+func (r *Recorder) Symbols() printer.Symbols { return r.Theme().Symbols }
+
+// Emoji always returns an empty string.
+func (r *Recorder) Emoji(string) string { return "" }
+
+// Link returns "text (url)", or url if text is empty or equal to it.
+func (r *Recorder) Link(text, url string) string {
+	if text == "" || text == url {
+		return url
+	}
+	return text + " (" + url + ")"
+}
+
+func (r *Recorder) Out() io.Writer { return (*writer)(r) }
+
+func (r *Recorder) Printf(format string, i ...interface{}) {
+	_, _ = fmt.Fprintf(r.Out(), format, i...)
+}
+
+func (r *Recorder) Println(i ...interface{}) {
+	_, _ = fmt.Fprintln(r.Out(), i...)
+}
+
+func (r *Recorder) Print(i ...interface{}) {
+	_, _ = fmt.Fprint(r.Out(), i...)
+}
+
+// Flush does nothing, output is recorded as it is written.
+func (r *Recorder) Flush() {}
+
+// writer records writes to the output of a Recorder.
+type writer Recorder
+
+func (w *writer) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.humanOut != nil {
+		if _, err := w.humanOut.Write(b); err != nil {
+			return 0, err
+		}
+	}
+	return w.out.Write(b)
+}
+
+func (r *Recorder) Success(format string, a ...interface{}) {
+	r.message(Message{Level: printer.LevelSuccess, Text: fmt.Sprintf(format, a...)})
+}
+
+func (r *Recorder) Info(format string, a ...interface{}) {
+	r.message(Message{Level: printer.LevelInfo, Text: fmt.Sprintf(format, a...)})
+}
+
+func (r *Recorder) Warning(format string, a ...interface{}) {
+	r.message(Message{Level: printer.LevelWarning, Text: fmt.Sprintf(format, a...)})
+}
+
+func (r *Recorder) Failure(format string, a ...interface{}) {
+	r.message(Message{Level: printer.LevelError, Text: fmt.Sprintf(format, a...)})
+}
+
+func (r *Recorder) Step(n, total int, format string, a ...interface{}) {
+	r.message(Message{Level: printer.LevelStep, Text: fmt.Sprintf(format, a...), Step: n, Total: total})
+}
+
+func (r *Recorder) Hint(format string, a ...interface{}) {
+	r.message(Message{Level: printer.LevelHint, Text: fmt.Sprintf(format, a...)})
+}
+
+func (r *Recorder) message(m Message) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.messages = append(r.messages, m)
+}
+
+func (r *Recorder) PrintResource(v interface{}) error { return r.resource("PrintResource", v) }
+
+func (r *Recorder) PrintJSON(v interface{}) error { return r.resource("PrintJSON", v) }
+
+func (r *Recorder) PrintYAML(v interface{}) error { return r.resource("PrintYAML", v) }
+
+func (r *Recorder) PrintCSV(v interface{}) error { return r.resource("PrintCSV", v) }
+
+// PrettyPrintJSON records b as a json.RawMessage. It fails if b isn't valid
+// JSON, like Printer.PrettyPrintJSON.
+func (r *Recorder) PrettyPrintJSON(b []byte) error {
+	if !json.Valid(b) {
+		return fmt.Errorf("invalid JSON: %q", b)
+	}
+	return r.resource("PrettyPrintJSON", json.RawMessage(b))
+}
+
+func (r *Recorder) resource(method string, v interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.resources = append(r.resources, Resource{Method: method, Value: v})
+	return nil
+}
+
+// PrintProgress records the start of a progress. The returned
+// printer.Progress records its updates and result.
+func (r *Recorder) PrintProgress(message string) printer.Progress {
+	return r.PrintProgressContext(context.Background(), message)
+}
+
+// PrintProgressContext is like PrintProgress. The progress isn't stopped
+// when ctx is done, so that the recorded events don't depend on timing.
+func (r *Recorder) PrintProgressContext(_ context.Context, message string) printer.Progress {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	pr := &progress{r: r, index: r.started, message: message}
+	r.started++
+	r.progress = append(r.progress, ProgressEvent{Progress: pr.index, Event: "start", Message: message})
+	return pr
+}
+
+func (r *Recorder) ConfirmCommand(confirmationName, commandShortName, confirmFailedName string) error {
+	return r.ConfirmCommandContext(context.Background(), confirmationName, commandShortName, confirmFailedName)
+}
+
+// ConfirmCommandContext uses the next scripted answer, which is either the
+// typed confirmation name or whether the command is confirmed. Like a
+// Printer, it fails without prompting if the format isn't human-readable or
+// the Recorder isn't interactive.
+func (r *Recorder) ConfirmCommandContext(_ context.Context, confirmationName, commandShortName, confirmFailedName string) error {
+	if err := r.checkConfirm(confirmationName, commandShortName, confirmFailedName); err != nil {
+		return err
+	}
+
+	answer, err := r.prompt(Prompt{Method: "ConfirmCommand", Message: confirmationName}, func() (interface{}, bool) {
+		return true, true
+	})
+	if err != nil {
+		return err
+	}
+
+	switch answer := answer.(type) {
+	case bool:
+		if answer {
+			return nil
+		}
+	case string:
+		if answer == confirmationName {
+			return nil
+		}
+	default:
+		return fmt.Errorf("invalid answer %#v for ConfirmCommand", answer)
+	}
+
+	return fmt.Errorf("incorrect value entered, skipping %s", commandShortName)
+}
+
+// checkConfirm returns the error of Printer.ConfirmCommand when it can't ask
+// for a confirmation.
+func (r *Recorder) checkConfirm(confirmationName, commandShortName, confirmFailedName string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	switch {
+	case r.assumeYes:
+		return nil
+	case r.format != printer.Human:
+		return fmt.Errorf("cannot %s with the output format %q", commandShortName, r.format.String())
+	case r.noninteractive:
+		return fmt.Errorf("cannot confirm %s %q", confirmFailedName, confirmationName)
+	}
+
+	return nil
+}
+
+func (r *Recorder) Input(_ context.Context, flag, message, defaultValue string, validators ...printer.Validator) (string, error) {
+	value, err := promptString(r, Prompt{Method: "Input", Flag: flag, Message: message, Default: defaultValue}, func() (interface{}, bool) {
+		return defaultValue, defaultValue != ""
+	})
+	if err != nil {
+		return "", err
+	}
+
+	return value, validate(value, validators)
+}
+
+func (r *Recorder) Password(_ context.Context, flag, message string, validators ...printer.Validator) (string, error) {
+	value, err := promptString(r, Prompt{Method: "Password", Flag: flag, Message: message}, nil)
+	if err != nil {
+		return "", err
+	}
+
+	return value, validate(value, validators)
+}
+
+func (r *Recorder) Select(_ context.Context, flag, message string, options []string, defaultValue string) (string, error) {
+	value, err := promptString(r, Prompt{Method: "Select", Flag: flag, Message: message, Options: options, Default: defaultValue}, func() (interface{}, bool) {
+		return defaultValue, defaultValue != ""
+	})
+	if err != nil {
+		return "", err
+	}
+
+	if !slices.Contains(options, value) {
+		return "", fmt.Errorf("answer %q is not one of the options %q", value, options)
+	}
+	return value, nil
+}
+
+func (r *Recorder) MultiSelect(_ context.Context, flag, message string, options []string, defaultValues []string) ([]string, error) {
+	answer, err := r.prompt(Prompt{Method: "MultiSelect", Flag: flag, Message: message, Options: options, Default: defaultValues}, func() (interface{}, bool) {
+		return defaultValues, defaultValues != nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	values, ok := answer.([]string)
+	if !ok {
+		return nil, fmt.Errorf("invalid answer %#v for MultiSelect", answer)
+	}
+
+	for _, v := range values {
+		if !slices.Contains(options, v) {
+			return nil, fmt.Errorf("answer %q is not one of the options %q", v, options)
+		}
+	}
+	return values, nil
+}
+
+func (r *Recorder) Confirm(_ context.Context, flag, message string, defaultValue bool) (bool, error) {
+	answer, err := r.prompt(Prompt{Method: "Confirm", Flag: flag, Message: message, Default: defaultValue}, func() (interface{}, bool) {
+		return true, true
+	})
+	if err != nil {
+		return false, err
+	}
+
+	value, ok := answer.(bool)
+	if !ok {
+		return false, fmt.Errorf("invalid answer %#v for Confirm", answer)
+	}
+	return value, nil
+}
+
+// OpenBrowser records url without opening it.
+func (r *Recorder) OpenBrowser(url string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.urls = append(r.urls, url)
+	return nil
+}
+
+// prompt records p and returns the next scripted answer. If assumeYes
+// returns true, its answer is used when SetAssumeYes is enabled.
+func (r *Recorder) prompt(p Prompt, assumeYes func() (interface{}, bool)) (interface{}, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.prompts = append(r.prompts, p)
+
+	if r.assumeYes && assumeYes != nil {
+		if answer, ok := assumeYes(); ok {
+			return answer, nil
+		}
+	}
+
+	switch {
+	case r.noninteractive:
+		return nil, &printer.PromptError{Flag: p.Flag, Reason: "in non-interactive mode"}
+	case r.format != printer.Human:
+		return nil, &printer.PromptError{Flag: p.Flag, Reason: fmt.Sprintf("with the output format %q", r.format.String())}
+	}
+
+	if len(r.answers) == 0 {
+		return nil, &printer.PromptError{Flag: p.Flag, Reason: "without a scripted answer"}
+	}
+
+	answer := r.answers[0]
+	r.answers = r.answers[1:]
+
+	if err, ok := answer.(error); ok {
+		return nil, err
+	}
+	return answer, nil
+}
+
+func promptString(r *Recorder, p Prompt, assumeYes func() (interface{}, bool)) (string, error) {
+	answer, err := r.prompt(p, assumeYes)
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := answer.(string)
+	if !ok {
+		return "", fmt.Errorf("invalid answer %#v for %s", answer, p.Method)
+	}
+	return value, nil
+}
+
+func validate(value string, validators []printer.Validator) error {
+	for _, v := range validators {
+		if err := v(value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// progress records the events of a progress started with PrintProgress.
+type progress struct {
+	r       *Recorder
+	index   int
+	message string
+	stopped bool
+	result  bool
+}
+
+func (pr *progress) event(event, message string) {
+	pr.r.progress = append(pr.r.progress, ProgressEvent{Progress: pr.index, Event: event, Message: message})
+}
+
+func (pr *progress) Update(format string, a ...interface{}) {
+	pr.r.mu.Lock()
+	defer pr.r.mu.Unlock()
+
+	pr.message = fmt.Sprintf(format, a...)
+	pr.event("update", pr.message)
+}
+
+// Elapsed always returns zero, so that recordings don't depend on timing.
+func (pr *progress) Elapsed() time.Duration { return 0 }
+
+func (pr *progress) Stop() {
+	pr.r.mu.Lock()
+	defer pr.r.mu.Unlock()
+
+	if !pr.stopped {
+		pr.stopped = true
+		pr.event("stop", pr.message)
+	}
+}
+
+func (pr *progress) Succeed(format string, a ...interface{}) {
+	pr.finish(printer.LevelSuccess, format, a...)
+}
+
+func (pr *progress) Fail(format string, a ...interface{}) {
+	pr.finish(printer.LevelError, format, a...)
+}
+
+func (pr *progress) Warn(format string, a ...interface{}) {
+	pr.finish(printer.LevelWarning, format, a...)
+}
+
+// finish records the result of the progress, like Printer only the first
+// one.
+func (pr *progress) finish(level, format string, a ...interface{}) {
+	pr.r.mu.Lock()
+	defer pr.r.mu.Unlock()
+
+	pr.stopped = true
+	if pr.result {
+		return
+	}
+	pr.result = true
+
+	message := pr.message
+	if format != "" {
+		message = fmt.Sprintf(format, a...)
+	}
+	pr.event(level, message)
+}
@@ -0,0 +1,145 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package printertest
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/loopholelabs/cmdutils/pkg/printer"
+)
+
+// deploy is an example of a command body that only depends on
+// printer.Interface.
+func deploy(ctx context.Context, p printer.Interface) error {
+	name, err := p.Input(ctx, "name", "Name", "")
+	if err != nil {
+		return err
+	}
+
+	region, err := p.Select(ctx, "region", "Region", []string{"us", "eu"}, "us")
+	if err != nil {
+		return err
+	}
+
+	ok, err := p.Confirm(ctx, "yes", "Deploy "+name+"?", false)
+	if err != nil || !ok {
+		return err
+	}
+
+	progress := p.PrintProgressContext(ctx, "Deploying")
+	progress.Update("Deploying to %s", region)
+	progress.Succeed("Deployed %s", name)
+
+	p.Hint("Run `myapp logs %s` to see the logs", name)
+	return p.PrintResource(map[string]string{"name": name, "region": region})
+}
+
+func TestRecorder(t *testing.T) {
+	r := NewRecorder()
+	r.Answer("web", "eu", true)
+
+	require.NoError(t, deploy(context.Background(), r))
+
+	require.Equal(t, []Prompt{
+		{Method: "Input", Flag: "name", Message: "Name", Default: ""},
+		{Method: "Select", Flag: "region", Message: "Region", Options: []string{"us", "eu"}, Default: "us"},
+		{Method: "Confirm", Flag: "yes", Message: "Deploy web?", Default: false},
+	}, r.Prompts())
+
+	require.Equal(t, []ProgressEvent{
+		{Progress: 0, Event: "start", Message: "Deploying"},
+		{Progress: 0, Event: "update", Message: "Deploying to eu"},
+		{Progress: 0, Event: printer.LevelSuccess, Message: "Deployed web"},
+	}, r.ProgressEvents())
+
+	require.Equal(t, []Message{
+		{Level: printer.LevelHint, Text: "Run `myapp logs web` to see the logs"},
+	}, r.Messages())
+
+	require.Equal(t, []Resource{
+		{Method: "PrintResource", Value: map[string]string{"name": "web", "region": "eu"}},
+	}, r.Resources())
+}
+
+func TestRecorderPromptErrors(t *testing.T) {
+	ctx := context.Background()
+
+	r := NewRecorder()
+	_, err := r.Input(ctx, "name", "Name", "")
+	require.ErrorIs(t, err, printer.ErrCannotPrompt)
+
+	r.Answer("mars")
+	_, err = r.Select(ctx, "region", "Region", []string{"us", "eu"}, "")
+	require.Error(t, err)
+
+	r.Answer(printer.ErrPromptInterrupted)
+	_, err = r.Confirm(ctx, "yes", "Continue?", false)
+	require.ErrorIs(t, err, context.Canceled)
+
+	r.Answer("")
+	_, err = r.Input(ctx, "name", "Name", "", func(s string) error {
+		if s == "" {
+			return errors.New("required")
+		}
+		return nil
+	})
+	require.EqualError(t, err, "required")
+
+	r.SetInteractive(false)
+	_, err = r.Password(ctx, "token", "Token")
+	var promptErr *printer.PromptError
+	require.ErrorAs(t, err, &promptErr)
+	require.Equal(t, "token", promptErr.Flag)
+
+	require.EqualError(t, r.ConfirmCommand("web", "delete", "deletion"), `cannot confirm deletion "web"`)
+
+	r.SetInteractive(true)
+	r.SetFormat(printer.JSON)
+	require.False(t, r.Interactive())
+	_, err = r.Input(ctx, "name", "Name", "")
+	require.EqualError(t, err, `cannot prompt for input with the output format "json" (run with --name to provide a value)`)
+	require.EqualError(t, r.ConfirmCommand("web", "delete", "deletion"), `cannot delete with the output format "json"`)
+
+	r.SetAssumeYes(true)
+	require.NoError(t, r.ConfirmCommand("web", "delete", "deletion"))
+	region, err := r.Select(ctx, "region", "Region", []string{"us", "eu"}, "eu")
+	require.NoError(t, err)
+	require.Equal(t, "eu", region)
+}
+
+// redirect is an example of a command body that redirects the output of
+// the printer through printer.Interface.
+func redirect(p printer.Interface, human, resources *bytes.Buffer) error {
+	p.SetHumanOutput(human)
+	p.SetResourceOutput(resources)
+	p.SetResourceFormat(printer.JSON)
+
+	p.Println("Listing")
+	return p.PrintResource(map[string]string{"name": "web"})
+}
+
+func TestRecorderSetters(t *testing.T) {
+	var human, resources bytes.Buffer
+
+	format := printer.Human
+	require.NoError(t, redirect(printer.NewPrinter(&format), &human, &resources))
+	require.Equal(t, "Listing\n", human.String())
+	require.JSONEq(t, `{"name":"web"}`, resources.String())
+
+	human.Reset()
+	resources.Reset()
+
+	r := NewRecorder()
+	require.NoError(t, redirect(r, &human, &resources))
+	require.Equal(t, "Listing\n", r.Output())
+	require.Equal(t, "Listing\n", human.String())
+	require.Equal(t, printer.JSON, r.ResourceFormat())
+	require.Equal(t, []Resource{
+		{Method: "PrintResource", Value: map[string]string{"name": "web"}},
+	}, r.Resources())
+}
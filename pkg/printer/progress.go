@@ -0,0 +1,218 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package printer
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/briandowns/spinner"
+)
+
+// elapsedThreshold is how long a spinner runs before it shows the elapsed
+// time.
+const elapsedThreshold = 3 * time.Second
+
+// Progress is an operation in progress started by PrintProgress.
+type Progress interface {
+	// Update changes the message of the progress.
+	Update(format string, a ...interface{})
+
+	// Elapsed returns the time since the progress started.
+	Elapsed() time.Duration
+
+	// Stop stops the progress without printing a result. It is safe to call
+	// Stop more than once, and after one of the result methods.
+	Stop()
+
+	// Succeed stops the progress and prints a success line with the given
+	// message, or the current message if format is empty, and the elapsed
+	// time.
+	Succeed(format string, a ...interface{})
+
+	// Fail stops the progress and prints an error line, like Succeed.
+	Fail(format string, a ...interface{})
+
+	// Warn stops the progress and prints a warning line, like Succeed.
+	Warn(format string, a ...interface{})
+}
+
+// progress is displayed as a spinner on terminals that can animate it, and
+// as a line when it starts and finishes otherwise.
+type progress struct {
+	p       *Printer
+	start   time.Time
+	spinner *spinner.Spinner
+
+	mu      sync.Mutex
+	message string
+
+	done       chan struct{}
+	stopOnce   sync.Once
+	resultOnce sync.Once
+}
+
+// PrintProgress starts a spinner with the relevant message. The returned
+// Progress needs to be stopped, with Stop or one of the result methods, in a
+// defer or when the operation finishes. Anything printed while the spinner
+// is active is written above it. The spinner is also stopped when the
+// context set with SetContext is done.
+func (p *Printer) PrintProgress(message string) Progress {
+	return p.PrintProgressContext(p.context(), message)
+}
+
+// PrintProgressContext is like PrintProgress, but also stops the spinner
+// when ctx is done.
+func (p *Printer) PrintProgressContext(ctx context.Context, message string) Progress {
+	pr := &progress{
+		p:       p,
+		start:   time.Now(),
+		message: message,
+		done:    make(chan struct{}),
+	}
+
+	p.mu.Lock()
+	caps := p.Capabilities()
+	if !caps.CanAnimate() || p.spinner != nil {
+		p.flushLocked()
+		_ = p.writeLocked(p.out(), []byte(message+"\n"))
+	} else {
+		p.flushLocked()
+
+		s := spinner.New(p.spinnerCharset(caps), 100*time.Millisecond, spinner.WithWriter(p.out()))
+		s.Suffix = " " + message
+
+		// The charset is already styled by the theme.
+		_ = s.Color("reset")
+
+		// PreUpdate is called with the spinner locked before every frame.
+		s.PreUpdate = func(s *spinner.Spinner) {
+			s.Suffix = " " + pr.suffix()
+		}
+
+		s.Start()
+		p.spinner = s
+		pr.spinner = s
+	}
+	p.mu.Unlock()
+
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				pr.Stop()
+			case <-pr.done:
+			}
+		}()
+	}
+
+	return pr
+}
+
+// suffix returns the message of the spinner, with the elapsed time once it
+// has been running for a while.
+func (pr *progress) suffix() string {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	elapsed := time.Since(pr.start)
+	if elapsed < elapsedThreshold {
+		return pr.message
+	}
+
+	return pr.message + " " + pr.p.Theme().Muted.Sprintf("(%s)", elapsed.Truncate(time.Second))
+}
+
+// Update changes the message of the spinner. Without a terminal the new
+// message is only used by the finish line.
+func (pr *progress) Update(format string, a ...interface{}) {
+	pr.mu.Lock()
+	defer pr.mu.Unlock()
+
+	pr.message = fmt.Sprintf(format, a...)
+}
+
+func (pr *progress) Elapsed() time.Duration {
+	return time.Since(pr.start)
+}
+
+func (pr *progress) Stop() {
+	pr.stopOnce.Do(func() {
+		close(pr.done)
+
+		if pr.spinner == nil {
+			return
+		}
+
+		p := pr.p
+		p.mu.Lock()
+		defer p.mu.Unlock()
+
+		pr.spinner.Stop()
+		p.spinner = nil
+
+		// NOTE(fatih) the spinner library doesn't clear the line properly,
+		// hence remove it ourselves. This line should be removed once it's
+		// fixed in upstream.  https://github.com/briandowns/spinner/pull/117
+		_, _ = fmt.Fprint(pr.spinner.Writer, "\r\033[2K")
+	})
+}
+
+func (pr *progress) Succeed(format string, a ...interface{}) {
+	pr.finish(LevelSuccess, format, a...)
+}
+
+func (pr *progress) Fail(format string, a ...interface{}) {
+	pr.finish(LevelError, format, a...)
+}
+
+func (pr *progress) Warn(format string, a ...interface{}) {
+	pr.finish(LevelWarning, format, a...)
+}
+
+// finish stops the spinner and prints its result. Only the first result is
+// printed.
+func (pr *progress) finish(level string, format string, a ...interface{}) {
+	pr.Stop()
+
+	pr.resultOnce.Do(func() {
+		pr.mu.Lock()
+		message := pr.message
+		pr.mu.Unlock()
+
+		if format != "" {
+			message = fmt.Sprintf(format, a...)
+		}
+
+		e := Event{
+			Level:   level,
+			Message: message,
+			Elapsed: formatElapsed(time.Since(pr.start)),
+		}
+
+		p := pr.p
+		symbols, theme := p.Symbols(), p.Theme()
+		switch level {
+		case LevelSuccess:
+			p.message(e, symbols.Success, theme.Success, nil)
+		case LevelWarning:
+			p.message(e, symbols.Warning, theme.Warning, nil)
+		default:
+			p.message(e, symbols.Error, theme.Error, nil)
+		}
+	})
+}
+
+// formatElapsed rounds d to a precision that is useful to humans.
+func formatElapsed(d time.Duration) string {
+	switch {
+	case d < time.Second:
+		return d.Round(time.Millisecond).String()
+	case d < time.Minute:
+		return d.Round(100 * time.Millisecond).String()
+	}
+
+	return d.Round(time.Second).String()
+}
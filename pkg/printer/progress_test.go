@@ -0,0 +1,83 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package printer
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestProgressNoninteractive(t *testing.T) {
+	clearCapabilityEnv(t)
+
+	var out bytes.Buffer
+	format := Human
+	p := NewPrinter(&format)
+	p.SetHumanOutput(&out)
+
+	pr := p.PrintProgress("Deploying")
+	pr.Update("Deploying %d/%d", 1, 2)
+	pr.Succeed("")
+	pr.Fail("ignored")
+	pr.Stop()
+
+	require.Regexp(t, `^Deploying\n\[ok\] Deploying 1/2 \(\d+(\.\d+)?[µnm]?s\)\n$`, out.String())
+
+	out.Reset()
+	p.PrintProgress("Checking").Warn("Check skipped")
+	require.Regexp(t, `^Checking\n\[warn\] Check skipped \(.+\)\n$`, out.String())
+}
+
+func TestProgressJSON(t *testing.T) {
+	var events bytes.Buffer
+	format := JSON
+	p := NewPrinter(&format)
+	p.SetEventOutput(&events)
+
+	p.PrintProgress("Deploying").Fail("Deployment failed")
+
+	var e Event
+	require.NoError(t, json.Unmarshal(events.Bytes(), &e))
+	require.Equal(t, LevelError, e.Level)
+	require.Equal(t, "Deployment failed", e.Message)
+	require.NotEmpty(t, e.Elapsed)
+}
+
+func TestProgressContext(t *testing.T) {
+	format := Human
+	p := NewPrinter(&format)
+	p.SetHumanOutput(&bytes.Buffer{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pr := p.PrintProgressContext(ctx, "Waiting")
+	cancel()
+
+	select {
+	case <-pr.(*progress).done:
+	case <-time.After(time.Second):
+		t.Fatal("progress wasn't stopped when its context was canceled")
+	}
+
+	// PrintProgress uses the context of the printer.
+	ctx, cancel = context.WithCancel(context.Background())
+	p.SetContext(ctx)
+	pr = p.PrintProgress("Waiting")
+	cancel()
+
+	select {
+	case <-pr.(*progress).done:
+	case <-time.After(time.Second):
+		t.Fatal("progress wasn't stopped when the context of the printer was canceled")
+	}
+}
+
+func TestFormatElapsed(t *testing.T) {
+	require.Equal(t, "12ms", formatElapsed(12345*time.Microsecond))
+	require.Equal(t, "1.2s", formatElapsed(1234*time.Millisecond))
+	require.Equal(t, "1m35s", formatElapsed(95400*time.Millisecond))
+}
@@ -0,0 +1,374 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package printer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/AlecAivazis/survey/v2"
+	"github.com/AlecAivazis/survey/v2/core"
+	"github.com/AlecAivazis/survey/v2/terminal"
+)
+
+var (
+	// ErrCannotPrompt is matched by every PromptError.
+	ErrCannotPrompt = errors.New("cannot prompt for input")
+
+	// ErrPromptInterrupted is returned when the user interrupts a prompt
+	// with Ctrl-C. It matches context.Canceled.
+	ErrPromptInterrupted = &canceledError{msg: "prompt interrupted", err: context.Canceled}
+)
+
+// isPromptTTY reports whether prompts, which read stdin and write to stdout,
+// run in a terminal.
+func isPromptTTY() bool {
+	return isTerminal(os.Stdin) && isTerminal(os.Stdout)
+}
+
+// PromptError is returned by the prompt helpers when they can't ask the user
+// for a value. Flag names the flag that can be used to provide the value
+// instead.
+type PromptError struct {
+	Flag   string
+	Reason string
+}
+
+func (e *PromptError) Error() string {
+	if e.Flag == "" {
+		return fmt.Sprintf("cannot prompt for input %s", e.Reason)
+	}
+	return fmt.Sprintf("cannot prompt for input %s (run with --%s to provide a value)", e.Reason, e.Flag)
+}
+
+func (e *PromptError) Is(target error) bool { return target == ErrCannotPrompt }
+
+// canceledError is returned when a prompt is aborted before the user
+// answered it. It matches context.Canceled, and the error that caused it.
+type canceledError struct {
+	msg   string
+	err   error
+	cause error
+}
+
+func (e *canceledError) Error() string { return e.msg }
+
+func (e *canceledError) Unwrap() []error {
+	if e.cause == nil {
+		return []error{e.err}
+	}
+	return []error{e.err, e.cause}
+}
+
+// stdin reads the standard input for all prompts.
+var stdin = newFileReader(os.Stdin)
+
+// surveyMu serializes prompts of different printers, which set the global
+// colour setting of survey.
+var surveyMu sync.Mutex
+
+// fileReader reads a file in a single background goroutine, so that prompts
+// can stop waiting for input without abandoning a read. Input read after a
+// prompt stopped waiting is kept for the next one.
+type fileReader struct {
+	file *os.File
+
+	mu       sync.Mutex
+	once     sync.Once
+	requests chan struct{}
+	results  chan readResult
+	waiting  bool
+	buf      []byte
+	err      error
+}
+
+type readResult struct {
+	buf []byte
+	err error
+}
+
+func newFileReader(file *os.File) *fileReader {
+	return &fileReader{
+		file:     file,
+		requests: make(chan struct{}, 1),
+		results:  make(chan readResult, 1),
+	}
+}
+
+// read reads into b until ctx is done.
+func (r *fileReader) read(ctx context.Context, b []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.buf) == 0 && r.err == nil {
+		r.once.Do(func() { go r.run() })
+
+		// A read of an earlier prompt may still be in progress.
+		if !r.waiting {
+			r.waiting = true
+			r.requests <- struct{}{}
+		}
+
+		select {
+		case res := <-r.results:
+			r.waiting = false
+			r.buf, r.err = res.buf, res.err
+		case <-ctx.Done():
+			return 0, ctx.Err()
+		}
+	}
+
+	n := copy(b, r.buf)
+	r.buf = r.buf[n:]
+	if len(r.buf) > 0 {
+		return n, nil
+	}
+
+	err := r.err
+	r.err = nil
+	return n, err
+}
+
+// run reads the file when a prompt waits for input.
+func (r *fileReader) run() {
+	for range r.requests {
+		buf := make([]byte, 1024)
+		n, err := r.file.Read(buf)
+		r.results <- readResult{buf: buf[:n], err: err}
+	}
+}
+
+// contextReader reads from a fileReader until its context is done.
+type contextReader struct {
+	ctx context.Context
+	in  *fileReader
+}
+
+func (r *contextReader) Fd() uintptr { return r.in.file.Fd() }
+
+func (r *contextReader) Read(b []byte) (int, error) {
+	return r.in.read(r.ctx, b)
+}
+
+// Validator checks a value entered at a prompt.
+type Validator func(value string) error
+
+// SetInteractive defines whether the printer is allowed to prompt the user
+// for input.
+func (p *Printer) SetInteractive(interactive bool) {
+	p.noninteractive = !interactive
+}
+
+// Interactive returns whether the printer is able to prompt the user for
+// input.
+func (p *Printer) Interactive() bool {
+	return p.checkPrompt("") == nil
+}
+
+// SetPromptTimeout sets how long prompts wait for an answer before failing
+// with an error matching context.Canceled and context.DeadlineExceeded. A
+// zero timeout waits until the context of the prompt is done.
+func (p *Printer) SetPromptTimeout(timeout time.Duration) {
+	p.promptTimeout = timeout
+}
+
+// SetAssumeYes makes confirmations succeed and other prompts accept their
+// default value without asking the user.
+func (p *Printer) SetAssumeYes(assumeYes bool) {
+	p.assumeYes = assumeYes
+}
+
+// AssumeYes returns whether prompts are answered automatically.
+func (p *Printer) AssumeYes() bool { return p.assumeYes }
+
+// SetAssumeYesHint sets the flag and environment variable that enable
+// SetAssumeYes, which are suggested by errors of unconfirmed commands.
+func (p *Printer) SetAssumeYesHint(flag, env string) {
+	p.assumeYesFlag = flag
+	p.assumeYesEnv = env
+}
+
+func (p *Printer) assumeYesHint() string {
+	switch {
+	case p.assumeYesFlag == "":
+		return ""
+	case p.assumeYesEnv == "":
+		return fmt.Sprintf(" (run with --%s to override)", p.assumeYesFlag)
+	}
+
+	return fmt.Sprintf(" (run with --%s or set %s=true to override)", p.assumeYesFlag, p.assumeYesEnv)
+}
+
+// checkPrompt returns a PromptError if the user can't be prompted for input.
+func (p *Printer) checkPrompt(flag string) error {
+	switch {
+	case p.noninteractive:
+		return &PromptError{Flag: flag, Reason: "in non-interactive mode"}
+	case p.Format() != Human:
+		return &PromptError{Flag: flag, Reason: fmt.Sprintf("with the output format %q", p.format)}
+	case !isPromptTTY():
+		return &PromptError{Flag: flag, Reason: "without a terminal"}
+	}
+
+	return nil
+}
+
+// ask runs the given prompt. Output of other goroutines and the active
+// spinner are held back until the prompt completes. The prompt is aborted
+// when ctx is done or the prompt timeout elapses.
+func (p *Printer) ask(ctx context.Context, flag string, prompt survey.Prompt, response interface{}, opts ...survey.AskOpt) error {
+	if err := p.checkPrompt(flag); err != nil {
+		return err
+	}
+
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	if p.promptTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.promptTimeout)
+		defer cancel()
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.flushLocked()
+	if p.spinner != nil {
+		p.spinner.Lock()
+		defer p.spinner.Unlock()
+		_, _ = fmt.Fprint(p.spinner.Writer, "\r\033[K")
+	}
+
+	in := &contextReader{ctx: ctx, in: stdin}
+	opts = append(opts, survey.WithStdio(in, os.Stdout, os.Stderr))
+
+	surveyMu.Lock()
+	core.DisableColor = !p.Capabilities().Color()
+	err := survey.AskOne(prompt, response, opts...)
+	surveyMu.Unlock()
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, terminal.InterruptErr):
+		return ErrPromptInterrupted
+	case ctx.Err() != nil:
+		// Move past the unanswered prompt.
+		_, _ = fmt.Fprintln(os.Stdout)
+
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) && p.promptTimeout > 0 {
+			return &canceledError{
+				msg:   fmt.Sprintf("prompt timed out after %s", p.promptTimeout),
+				err:   context.Canceled,
+				cause: ctx.Err(),
+			}
+		}
+		return &canceledError{msg: "prompt canceled", err: context.Canceled, cause: ctx.Err()}
+	}
+
+	return err
+}
+
+func withValidators(validators []Validator) []survey.AskOpt {
+	opts := make([]survey.AskOpt, 0, len(validators))
+	for _, v := range validators {
+		v := v
+		opts = append(opts, survey.WithValidator(func(ans interface{}) error {
+			return v(fmt.Sprint(ans))
+		}))
+	}
+	return opts
+}
+
+// Input asks the user to enter a value. An empty answer is replaced by
+// defaultValue, which is accepted without asking when SetAssumeYes is
+// enabled.
+func (p *Printer) Input(ctx context.Context, flag, message, defaultValue string, validators ...Validator) (string, error) {
+	if p.assumeYes && defaultValue != "" {
+		for _, v := range validators {
+			if err := v(defaultValue); err != nil {
+				return "", err
+			}
+		}
+		return defaultValue, nil
+	}
+
+	var value string
+	err := p.ask(ctx, flag, &survey.Input{
+		Message: message,
+		Default: defaultValue,
+	}, &value, withValidators(validators)...)
+	return value, err
+}
+
+// Password asks the user to enter a value without echoing it.
+func (p *Printer) Password(ctx context.Context, flag, message string, validators ...Validator) (string, error) {
+	var value string
+	err := p.ask(ctx, flag, &survey.Password{
+		Message: message,
+	}, &value, withValidators(validators)...)
+	return value, err
+}
+
+// Select asks the user to choose one of options. If defaultValue is not
+// empty, it is selected initially, or chosen without asking when
+// SetAssumeYes is enabled.
+func (p *Printer) Select(ctx context.Context, flag, message string, options []string, defaultValue string) (string, error) {
+	if p.assumeYes && defaultValue != "" {
+		return defaultValue, nil
+	}
+
+	prompt := &survey.Select{
+		Message: message,
+		Options: options,
+	}
+	if defaultValue != "" {
+		prompt.Default = defaultValue
+	}
+
+	var value string
+	err := p.ask(ctx, flag, prompt, &value)
+	return value, err
+}
+
+// MultiSelect asks the user to choose any number of options, with
+// defaultValues selected initially, or chosen without asking when
+// SetAssumeYes is enabled.
+func (p *Printer) MultiSelect(ctx context.Context, flag, message string, options []string, defaultValues []string) ([]string, error) {
+	if p.assumeYes && defaultValues != nil {
+		return defaultValues, nil
+	}
+
+	prompt := &survey.MultiSelect{
+		Message: message,
+		Options: options,
+	}
+	if len(defaultValues) > 0 {
+		prompt.Default = defaultValues
+	}
+
+	var values []string
+	err := p.ask(ctx, flag, prompt, &values)
+	return values, err
+}
+
+// Confirm asks the user a yes/no question. An empty answer is replaced by
+// defaultValue. When SetAssumeYes is enabled, Confirm returns true without
+// asking.
+func (p *Printer) Confirm(ctx context.Context, flag, message string, defaultValue bool) (bool, error) {
+	if p.assumeYes {
+		return true, nil
+	}
+
+	var value bool
+	err := p.ask(ctx, flag, &survey.Confirm{
+		Message: message,
+		Default: defaultValue,
+	}, &value)
+	return value, err
+}
@@ -0,0 +1,452 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package printer
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/briandowns/spinner"
+	"github.com/fatih/color"
+	"github.com/jedib0t/go-pretty/v6/table"
+	"github.com/jedib0t/go-pretty/v6/text"
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	errUnknownTheme      = errors.New("unknown theme")
+	errUnknownAttribute  = errors.New("unknown style attribute")
+	errUnknownTableStyle = errors.New("unknown table borders")
+)
+
+var attributes = map[string]color.Attribute{
+	"bold":      color.Bold,
+	"faint":     color.Faint,
+	"italic":    color.Italic,
+	"underline": color.Underline,
+	"reverse":   color.ReverseVideo,
+
+	"black":   color.FgBlack,
+	"red":     color.FgRed,
+	"green":   color.FgGreen,
+	"yellow":  color.FgYellow,
+	"blue":    color.FgBlue,
+	"magenta": color.FgMagenta,
+	"cyan":    color.FgCyan,
+	"white":   color.FgWhite,
+
+	"hi-black":   color.FgHiBlack,
+	"hi-red":     color.FgHiRed,
+	"hi-green":   color.FgHiGreen,
+	"hi-yellow":  color.FgHiYellow,
+	"hi-blue":    color.FgHiBlue,
+	"hi-magenta": color.FgHiMagenta,
+	"hi-cyan":    color.FgHiCyan,
+	"hi-white":   color.FgHiWhite,
+
+	"bg-black":   color.BgBlack,
+	"bg-red":     color.BgRed,
+	"bg-green":   color.BgGreen,
+	"bg-yellow":  color.BgYellow,
+	"bg-blue":    color.BgBlue,
+	"bg-magenta": color.BgMagenta,
+	"bg-cyan":    color.BgCyan,
+	"bg-white":   color.BgWhite,
+
+	"bg-hi-black":   color.BgHiBlack,
+	"bg-hi-red":     color.BgHiRed,
+	"bg-hi-green":   color.BgHiGreen,
+	"bg-hi-yellow":  color.BgHiYellow,
+	"bg-hi-blue":    color.BgHiBlue,
+	"bg-hi-magenta": color.BgHiMagenta,
+	"bg-hi-cyan":    color.BgHiCyan,
+	"bg-hi-white":   color.BgHiWhite,
+}
+
+var tableBorders = map[string]table.BoxStyle{
+	"none":    table.StyleBoxDefault,
+	"default": table.StyleBoxDefault,
+	"light":   table.StyleBoxLight,
+	"rounded": table.StyleBoxRounded,
+	"bold":    table.StyleBoxBold,
+	"double":  table.StyleBoxDouble,
+}
+
+// Style is a list of text attributes, such as "bold", "red" or
+// "bg-hi-white", applied to text printed in a given role.
+type Style []string
+
+func (s Style) validate() error {
+	for _, a := range s {
+		if _, ok := attributes[a]; !ok {
+			return fmt.Errorf("%w: %q", errUnknownAttribute, a)
+		}
+	}
+	return nil
+}
+
+// color returns the colour of the style. Whether colours are enabled is a
+// setting of each printer, whose Theme has no styles if they aren't, so the
+// global setting of fatih/color is ignored.
+func (s Style) color() *color.Color {
+	c := color.New()
+	for _, a := range s {
+		c.Add(attributes[a])
+	}
+	c.EnableColor()
+	return c
+}
+
+// Sprint formats its arguments like fmt.Sprint and applies the style. The
+// styles of Printer.Theme are empty if colour output is disabled.
+func (s Style) Sprint(a ...interface{}) string {
+	if len(s) == 0 {
+		return fmt.Sprint(a...)
+	}
+	return s.color().Sprint(a...)
+}
+
+// Sprintf formats its arguments like fmt.Sprintf and applies the style. The
+// styles of Printer.Theme are empty if colour output is disabled.
+func (s Style) Sprintf(format string, a ...interface{}) string {
+	if len(s) == 0 {
+		return fmt.Sprintf(format, a...)
+	}
+	return s.color().Sprintf(format, a...)
+}
+
+// textColors converts the style to the colours used by tables. Both use the
+// same SGR parameters.
+func (s Style) textColors() text.Colors {
+	if len(s) == 0 {
+		return nil
+	}
+
+	colors := make(text.Colors, 0, len(s))
+	for _, a := range s {
+		colors = append(colors, text.Color(attributes[a]))
+	}
+	return colors
+}
+
+// Symbols are the status symbols printed in front of messages.
+type Symbols struct {
+	Success string `yaml:"success"`
+	Warning string `yaml:"warning"`
+	Error   string `yaml:"error"`
+	Info    string `yaml:"info"`
+}
+
+// SpinnerTheme defines the appearance of progress spinners.
+type SpinnerTheme struct {
+	Charset []string `yaml:"charset"`
+	Style   Style    `yaml:"style"`
+}
+
+// TableTheme defines the appearance of tables printed by PrintResource. The
+// header row uses the Header role of the theme.
+type TableTheme struct {
+	// Borders is one of "none", "default", "light", "rounded", "bold" or
+	// "double". Tables without borders rely on colours to tell the header
+	// apart, so they are printed with default borders when colours are
+	// disabled.
+	Borders      string `yaml:"borders"`
+	Row          Style  `yaml:"row"`
+	RowAlternate Style  `yaml:"row-alternate"`
+}
+
+// SyntaxTheme defines the colours of highlighted JSON and YAML output.
+type SyntaxTheme struct {
+	Key    Style `yaml:"key"`
+	String Style `yaml:"string"`
+	Number Style `yaml:"number"`
+	Bool   Style `yaml:"bool"`
+	Null   Style `yaml:"null"`
+}
+
+// Theme defines the colours, symbols, spinner and table style used by a
+// Printer, by semantic role.
+type Theme struct {
+	Name string `yaml:"-"`
+
+	Success Style `yaml:"success"`
+	Warning Style `yaml:"warning"`
+	Error   Style `yaml:"error"`
+	Accent  Style `yaml:"accent"`
+	Muted   Style `yaml:"muted"`
+	Header  Style `yaml:"header"`
+
+	Symbols Symbols      `yaml:"symbols"`
+	Spinner SpinnerTheme `yaml:"spinner"`
+	Table   TableTheme   `yaml:"table"`
+	Syntax  SyntaxTheme  `yaml:"syntax"`
+}
+
+var defaultSymbols = Symbols{
+	Success: "✔",
+	Warning: "⚠",
+	Error:   "✘",
+	Info:    "ℹ",
+}
+
+var (
+	// DarkTheme is the default theme, designed for terminals with a dark
+	// background.
+	DarkTheme = &Theme{
+		Name:    "dark",
+		Success: Style{"bold", "green"},
+		Warning: Style{"bold", "yellow"},
+		Error:   Style{"bold", "red"},
+		Accent:  Style{"bold", "blue"},
+		Muted:   Style{"hi-black"},
+		Header:  Style{"bg-hi-magenta", "black"},
+		Symbols: defaultSymbols,
+		Spinner: SpinnerTheme{
+			Charset: spinner.CharSets[14],
+			Style:   Style{"bold", "magenta"},
+		},
+		Table: TableTheme{
+			Borders:      "none",
+			Row:          Style{"bg-hi-white", "black"},
+			RowAlternate: Style{"bg-white", "black"},
+		},
+		Syntax: SyntaxTheme{
+			Key:    Style{"bold", "blue"},
+			String: Style{"green"},
+			Number: Style{"cyan"},
+			Bool:   Style{"yellow"},
+			Null:   Style{"magenta"},
+		},
+	}
+
+	// LightTheme is designed for terminals with a light background.
+	LightTheme = &Theme{
+		Name:    "light",
+		Success: Style{"bold", "green"},
+		Warning: Style{"bold", "yellow"},
+		Error:   Style{"bold", "red"},
+		Accent:  Style{"bold", "blue"},
+		Muted:   Style{"hi-black"},
+		Header:  Style{"bold", "blue"},
+		Symbols: defaultSymbols,
+		Spinner: SpinnerTheme{
+			Charset: spinner.CharSets[14],
+			Style:   Style{"bold", "blue"},
+		},
+		Table: TableTheme{
+			Borders: "light",
+		},
+		Syntax: SyntaxTheme{
+			Key:    Style{"bold", "blue"},
+			String: Style{"green"},
+			Number: Style{"magenta"},
+			Bool:   Style{"red"},
+			Null:   Style{"hi-black"},
+		},
+	}
+
+	// MonoTheme doesn't use any colours.
+	MonoTheme = &Theme{
+		Name:    "mono",
+		Symbols: defaultSymbols,
+		Spinner: SpinnerTheme{
+			Charset: spinner.CharSets[14],
+		},
+		Table: TableTheme{
+			Borders: "default",
+		},
+	}
+
+	// DefaultTheme is used by printers without a theme.
+	DefaultTheme = DarkTheme
+)
+
+var themes = map[string]*Theme{
+	DarkTheme.Name:  DarkTheme,
+	LightTheme.Name: LightTheme,
+	MonoTheme.Name:  MonoTheme,
+}
+
+// LoadTheme returns the built-in theme with the given name ("dark", "light"
+// or "mono"), or loads a custom theme from the YAML file at the given path.
+// A custom theme may set "base" to the name of the built-in theme it
+// overrides, which defaults to the DefaultTheme.
+func LoadTheme(name string) (*Theme, error) {
+	if t, ok := themes[strings.ToLower(name)]; ok {
+		return t, nil
+	}
+
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".yaml", ".yml":
+	default:
+		names := make([]string, 0, len(themes))
+		for n := range themes {
+			names = append(names, n)
+		}
+		sort.Strings(names)
+		return nil, fmt.Errorf("%w %q. Valid values: %v or a path to a YAML file", errUnknownTheme, name, names)
+	}
+
+	b, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read theme: %w", err)
+	}
+
+	var base struct {
+		Base string `yaml:"base"`
+	}
+	if err := yaml.Unmarshal(b, &base); err != nil {
+		return nil, fmt.Errorf("failed to parse theme %q: %w", name, err)
+	}
+
+	t := *DefaultTheme
+	if base.Base != "" {
+		b, ok := themes[strings.ToLower(base.Base)]
+		if !ok {
+			return nil, fmt.Errorf("%w %q", errUnknownTheme, base.Base)
+		}
+		t = *b
+	}
+
+	if err := yaml.Unmarshal(b, &t); err != nil {
+		return nil, fmt.Errorf("failed to parse theme %q: %w", name, err)
+	}
+	t.Name = name
+
+	if err := t.validate(); err != nil {
+		return nil, fmt.Errorf("invalid theme %q: %w", name, err)
+	}
+
+	return &t, nil
+}
+
+func (t *Theme) validate() error {
+	styles := []Style{
+		t.Success, t.Warning, t.Error, t.Accent, t.Muted, t.Header,
+		t.Spinner.Style, t.Table.Row, t.Table.RowAlternate,
+		t.Syntax.Key, t.Syntax.String, t.Syntax.Number, t.Syntax.Bool, t.Syntax.Null,
+	}
+	for _, s := range styles {
+		if err := s.validate(); err != nil {
+			return err
+		}
+	}
+
+	if _, ok := tableBorders[t.Table.Borders]; !ok {
+		return fmt.Errorf("%w: %q", errUnknownTableStyle, t.Table.Borders)
+	}
+
+	if len(t.Spinner.Charset) == 0 {
+		return errors.New("spinner charset is empty")
+	}
+
+	return nil
+}
+
+// tableStyle returns the style of tables printed with this theme.
+func (t *Theme) tableStyle(colors bool) table.Style {
+	if !colors {
+		style := table.StyleDefault
+		if t.Table.Borders != "none" {
+			style.Box = tableBorders[t.Table.Borders]
+		}
+		return style
+	}
+
+	style := table.StyleDefault
+	style.Name = t.Name
+	style.Box = tableBorders[t.Table.Borders]
+	if t.Table.Borders == "none" {
+		style.Options = table.OptionsNoBordersAndSeparators
+	}
+	style.Color = table.ColorOptions{
+		Header:       t.Header.textColors(),
+		Footer:       t.Header.textColors(),
+		Row:          t.Table.Row.textColors(),
+		RowAlternate: t.Table.RowAlternate.textColors(),
+	}
+
+	return style
+}
+
+// ColorEnabled reports whether colour output to out should be enabled,
+// following the NO_COLOR, FORCE_COLOR and CLICOLOR_FORCE conventions, and
+// otherwise only enabling colours on terminals.
+func ColorEnabled(out io.Writer) bool {
+	return colorEnabled(isTerminal(out))
+}
+
+func colorEnabled(tty bool) bool {
+	if v := os.Getenv("NO_COLOR"); v != "" {
+		return false
+	}
+
+	if colorForced() {
+		return true
+	}
+
+	if os.Getenv("TERM") == "dumb" {
+		return false
+	}
+
+	return tty
+}
+
+// colorForced reports whether FORCE_COLOR or CLICOLOR_FORCE enable colours.
+func colorForced() bool {
+	for _, env := range []string{"FORCE_COLOR", "CLICOLOR_FORCE"} {
+		if v, ok := os.LookupEnv(env); ok && v != "0" && v != "false" {
+			return true
+		}
+	}
+	return false
+}
+
+func init() {
+	// fatih/color disables colours when stdout isn't a terminal, ignoring
+	// FORCE_COLOR and CLICOLOR_FORCE. Printers disable them per output, the
+	// global setting only applies to the deprecated helpers such as Bold.
+	if colorForced() && os.Getenv("NO_COLOR") == "" {
+		color.NoColor = false
+	}
+}
+
+// SetTheme sets the theme used by the printer.
+func (p *Printer) SetTheme(theme *Theme) {
+	p.theme = theme
+}
+
+// Theme returns the theme used by the printer, without colours if the
+// output for human-readable messages can't display them.
+func (p *Printer) Theme() *Theme {
+	return p.themeFor(p.Capabilities())
+}
+
+// themeFor returns the theme used by the printer for an output with the
+// given capabilities.
+func (p *Printer) themeFor(caps Capabilities) *Theme {
+	theme := p.theme
+	if theme == nil {
+		theme = DefaultTheme
+	}
+
+	if !caps.Color() {
+		return theme.withoutColors()
+	}
+	return theme
+}
+
+// withoutColors returns a copy of the theme without styles.
+func (t *Theme) withoutColors() *Theme {
+	return &Theme{
+		Name:    t.Name,
+		Symbols: t.Symbols,
+		Spinner: SpinnerTheme{Charset: t.Spinner.Charset},
+		Table:   TableTheme{Borders: t.Table.Borders},
+	}
+}
@@ -0,0 +1,74 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package printer
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadTheme(t *testing.T) {
+	for _, name := range []string{"dark", "light", "Mono"} {
+		theme, err := LoadTheme(name)
+		require.NoError(t, err)
+		require.NoError(t, theme.validate())
+	}
+
+	_, err := LoadTheme("solarized")
+	require.ErrorIs(t, err, errUnknownTheme)
+
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "brand.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`
+base: light
+accent: [bold, hi-magenta]
+symbols:
+  success: "+"
+table:
+  borders: rounded
+`), 0600))
+
+	theme, err := LoadTheme(path)
+	require.NoError(t, err)
+	require.Equal(t, path, theme.Name)
+	require.Equal(t, Style{"bold", "hi-magenta"}, theme.Accent)
+	require.Equal(t, "+", theme.Symbols.Success)
+	require.Equal(t, LightTheme.Symbols.Error, theme.Symbols.Error)
+	require.Equal(t, LightTheme.Header, theme.Header)
+	require.Equal(t, "rounded", theme.Table.Borders)
+
+	invalid := filepath.Join(dir, "invalid.yaml")
+	require.NoError(t, os.WriteFile(invalid, []byte(`accent: [sparkly]`), 0600))
+
+	_, err = LoadTheme(invalid)
+	require.ErrorIs(t, err, errUnknownAttribute)
+}
+
+func TestColorEnabled(t *testing.T) {
+	f, err := os.CreateTemp(t.TempDir(), "out")
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = f.Close() })
+
+	t.Setenv("NO_COLOR", "")
+	t.Setenv("FORCE_COLOR", "")
+	t.Setenv("CLICOLOR_FORCE", "")
+	require.NoError(t, os.Unsetenv("FORCE_COLOR"))
+	require.NoError(t, os.Unsetenv("CLICOLOR_FORCE"))
+	require.False(t, ColorEnabled(f))
+
+	t.Setenv("CLICOLOR_FORCE", "1")
+	require.True(t, ColorEnabled(f))
+
+	t.Setenv("CLICOLOR_FORCE", "0")
+	require.False(t, ColorEnabled(f))
+
+	t.Setenv("FORCE_COLOR", "1")
+	require.True(t, ColorEnabled(f))
+
+	t.Setenv("NO_COLOR", "1")
+	require.False(t, ColorEnabled(f))
+}
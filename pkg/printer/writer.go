@@ -0,0 +1,117 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package printer
+
+import (
+	"bytes"
+	"io"
+	"slices"
+)
+
+// lineWriter is the io.Writer returned by Printer.Out. It only writes
+// complete lines to the underlying output, so lines printed concurrently by
+// different goroutines are never torn apart. Every lineWriter keeps its own
+// partial line until it is completed or flushed.
+type lineWriter struct {
+	p *Printer
+
+	// out is the underlying output, or nil for the human output of the
+	// printer at the time of the write.
+	out     io.Writer
+	pending []byte
+}
+
+func (w *lineWriter) output() io.Writer {
+	if w.out != nil {
+		return w.out
+	}
+	return w.p.out()
+}
+
+func (w *lineWriter) Write(b []byte) (int, error) {
+	w.p.mu.Lock()
+	defer w.p.mu.Unlock()
+
+	w.pending = append(w.pending, b...)
+
+	i := bytes.LastIndexByte(w.pending, '\n')
+	if i == -1 {
+		if !slices.Contains(w.p.partial, w) {
+			w.p.partial = append(w.p.partial, w)
+		}
+		return len(b), nil
+	}
+
+	lines := w.pending[:i+1]
+	w.pending = append([]byte(nil), w.pending[i+1:]...)
+	if len(w.pending) == 0 {
+		w.p.partial = slices.DeleteFunc(w.p.partial, func(p *lineWriter) bool { return p == w })
+	}
+
+	if err := w.p.writeLocked(w.output(), lines); err != nil {
+		return 0, err
+	}
+
+	return len(b), nil
+}
+
+// lines returns the lineWriter used by Printf, Println and Print, so that
+// their partial lines are joined.
+func (p *Printer) lines() io.Writer {
+	if p.out() == io.Discard {
+		return io.Discard
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.lineWriter == nil {
+		p.lineWriter = &lineWriter{p: p}
+	}
+	return p.lineWriter
+}
+
+// write writes b to out, serialised with every other write of the printer.
+// Partial lines are flushed first, so that the output keeps the order of the
+// writes.
+func (p *Printer) write(out io.Writer, b []byte) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.flushLocked()
+	return p.writeLocked(out, b)
+}
+
+// writeLocked writes b to out, clearing the line of the active spinner
+// first, if there is one. The spinner is redrawn on its next frame. The
+// caller must hold p.mu.
+func (p *Printer) writeLocked(out io.Writer, b []byte) error {
+	if p.spinner == nil {
+		_, err := out.Write(b)
+		return err
+	}
+
+	p.spinner.Lock()
+	defer p.spinner.Unlock()
+
+	_, _ = io.WriteString(p.spinner.Writer, "\r\033[K")
+	_, err := out.Write(b)
+	return err
+}
+
+// Flush writes any partial line printed to Out that hasn't been completed
+// with a newline yet.
+func (p *Printer) Flush() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.flushLocked()
+}
+
+func (p *Printer) flushLocked() {
+	for _, w := range p.partial {
+		_ = p.writeLocked(w.output(), w.pending)
+		w.pending = nil
+	}
+	p.partial = nil
+}
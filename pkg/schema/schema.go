@@ -0,0 +1,264 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/loopholelabs/cmdutils"
+	"github.com/loopholelabs/cmdutils/pkg/config"
+)
+
+// Draft is the JSON Schema dialect emitted by Generate.
+const Draft = "https://json-schema.org/draft/2020-12/schema"
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// Schema is a JSON Schema document describing the JSON output of a command.
+type Schema struct {
+	Schema               string             `json:"$schema,omitempty"`
+	Ref                  string             `json:"$ref,omitempty"`
+	Defs                 map[string]*Schema `json:"$defs,omitempty"`
+	AnyOf                []*Schema          `json:"anyOf,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	ContentEncoding      string             `json:"contentEncoding,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Nullable             bool               `json:"-"`
+}
+
+// MarshalJSON encodes nullable schemas using a type array, as required by
+// JSON Schema for values that may be null.
+func (s *Schema) MarshalJSON() ([]byte, error) {
+	type schema Schema
+	if !s.Nullable || s.Type == "" {
+		return json.Marshal((*schema)(s))
+	}
+
+	return json.Marshal(struct {
+		*schema
+		Type []string `json:"type"`
+	}{
+		schema: (*schema)(s),
+		Type:   []string{s.Type, "null"},
+	})
+}
+
+// Generate returns the JSON Schema for values of type t, derived from the
+// same json struct tags that encoding/json uses to encode them. Recursive
+// types are defined under $defs and referenced with $ref.
+func Generate(t reflect.Type) *Schema {
+	g := &generator{
+		visiting:  make(map[reflect.Type]bool),
+		recursive: make(map[reflect.Type]bool),
+		names:     make(map[reflect.Type]string),
+		defs:      make(map[string]*Schema),
+	}
+
+	s := g.generate(t)
+	s.Schema = Draft
+	if len(g.defs) > 0 {
+		s.Defs = g.defs
+	}
+	return s
+}
+
+// generator keeps track of the struct types being expanded, so that
+// recursive types are defined once and referenced everywhere else.
+type generator struct {
+	visiting  map[reflect.Type]bool
+	recursive map[reflect.Type]bool
+	names     map[reflect.Type]string
+	defs      map[string]*Schema
+}
+
+func (g *generator) generate(t reflect.Type) *Schema {
+	if t == nil {
+		return &Schema{}
+	}
+
+	nullable := false
+	for t.Kind() == reflect.Pointer {
+		nullable = true
+		t = t.Elem()
+	}
+
+	s := g.generateType(t)
+	if s.Ref != "" && nullable {
+		// $ref can't be combined with a type array.
+		return &Schema{AnyOf: []*Schema{s, {Type: "null"}}}
+	}
+	s.Nullable = s.Nullable || nullable
+	return s
+}
+
+func (g *generator) generateType(t reflect.Type) *Schema {
+	switch {
+	case t == timeType:
+		return &Schema{Type: "string", Format: "date-time"}
+	case t.Implements(jsonMarshalerType), reflect.PointerTo(t).Implements(jsonMarshalerType):
+		// The encoding is entirely up to the type, so anything goes.
+		return &Schema{}
+	case t.Implements(textMarshalerType), reflect.PointerTo(t).Implements(textMarshalerType):
+		return &Schema{Type: "string"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return &Schema{Type: "integer"}
+	case reflect.Float32, reflect.Float64:
+		return &Schema{Type: "number"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", ContentEncoding: "base64", Nullable: true}
+		}
+		return &Schema{Type: "array", Items: g.generate(t.Elem()), Nullable: true}
+	case reflect.Array:
+		return &Schema{Type: "array", Items: g.generate(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.generate(t.Elem()), Nullable: true}
+	case reflect.Struct:
+		if g.visiting[t] {
+			// Recursive types cannot be expanded inline, so the type is
+			// defined under $defs once it has been expanded.
+			g.recursive[t] = true
+			return g.ref(t)
+		}
+		if g.recursive[t] {
+			return g.ref(t)
+		}
+		g.visiting[t] = true
+		defer delete(g.visiting, t)
+
+		s := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+		g.generateFields(s, t)
+		if g.recursive[t] {
+			ref := g.ref(t)
+			g.defs[strings.TrimPrefix(ref.Ref, "#/$defs/")] = s
+			return ref
+		}
+		return s
+	}
+
+	// Interfaces and any other kinds can hold arbitrary values.
+	return &Schema{}
+}
+
+// ref returns a reference to the definition of the named type t, naming it
+// after the type and numbering types of different packages sharing a name.
+func (g *generator) ref(t reflect.Type) *Schema {
+	name, ok := g.names[t]
+	if !ok {
+		name = t.Name()
+		taken := func(name string) bool {
+			for _, n := range g.names {
+				if n == name {
+					return true
+				}
+			}
+			return false
+		}
+		for i := 2; taken(name); i++ {
+			name = t.Name() + strconv.Itoa(i)
+		}
+		g.names[t] = name
+	}
+
+	return &Schema{Ref: "#/$defs/" + name}
+}
+
+// generateFields adds the fields of struct type t to s, flattening embedded
+// structs the same way encoding/json does.
+func (g *generator) generateFields(s *Schema, t reflect.Type) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if field.Anonymous && name == "" {
+			ft := field.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				g.generateFields(s, ft)
+				continue
+			}
+		}
+
+		if !field.IsExported() {
+			continue
+		}
+
+		if name == "" {
+			name = field.Name
+		}
+
+		s.Properties[name] = g.generate(field.Type)
+
+		omitEmpty := false
+		for _, opt := range strings.Split(opts, ",") {
+			if opt == "omitempty" {
+				omitEmpty = true
+			}
+		}
+		if !omitEmpty {
+			s.Required = append(s.Required, name)
+		}
+	}
+}
+
+// Cmd returns the hidden schema command, which prints the JSON Schema of the
+// output of every command that declared one with cmdutils.DeclareOutput.
+func Cmd[T config.Config](ch *cmdutils.Helper[T]) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:    "schema",
+		Short:  "Show the JSON Schema of every command's output",
+		Hidden: true,
+		Args:   cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			schemas := make(map[string]*Schema)
+			walk(cmd.Root(), func(c *cobra.Command) {
+				if t, ok := ch.Output(c); ok {
+					schemas[c.CommandPath()] = Generate(t)
+				}
+			})
+			return ch.Printer.PrintJSON(schemas)
+		},
+	}
+
+	cmdutils.SkipValidation(cmd)
+
+	return cmd
+}
+
+func walk(cmd *cobra.Command, fn func(*cobra.Command)) {
+	fn(cmd)
+	for _, c := range cmd.Commands() {
+		walk(c, fn)
+	}
+}
@@ -0,0 +1,97 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package schema
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+type testBase struct {
+	ID string `json:"id"`
+}
+
+type testResource struct {
+	testBase
+	Name      string            `json:"name"`
+	Count     int               `json:"count,omitempty"`
+	Ready     *bool             `json:"ready"`
+	Tags      []string          `json:"tags"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	CreatedAt time.Time         `json:"created_at"`
+	Children  []testResource    `json:"children,omitempty"`
+	Ignored   string            `json:"-"`
+	internal  string
+}
+
+func TestGenerate(t *testing.T) {
+	s := Generate(reflect.TypeOf([]testResource{}))
+
+	b, err := json.Marshal(s)
+	require.NoError(t, err)
+
+	expected := `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": ["array", "null"],
+		"items": {"$ref": "#/$defs/testResource"},
+		"$defs": {
+			"testResource": {
+				"type": "object",
+				"properties": {
+					"id": {"type": "string"},
+					"name": {"type": "string"},
+					"count": {"type": "integer"},
+					"ready": {"type": ["boolean", "null"]},
+					"tags": {"type": ["array", "null"], "items": {"type": "string"}},
+					"labels": {"type": ["object", "null"], "additionalProperties": {"type": "string"}},
+					"created_at": {"type": "string", "format": "date-time"},
+					"children": {"type": ["array", "null"], "items": {"$ref": "#/$defs/testResource"}}
+				},
+				"required": ["id", "name", "ready", "tags", "created_at"]
+			}
+		}
+	}`
+	require.JSONEq(t, expected, string(b))
+}
+
+type testNode struct {
+	Value  string    `json:"value"`
+	Parent *testNode `json:"parent"`
+}
+
+type testTree struct {
+	Root  testNode   `json:"root"`
+	Nodes []testNode `json:"nodes"`
+}
+
+func TestGenerateRecursive(t *testing.T) {
+	s := Generate(reflect.TypeOf(testTree{}))
+
+	b, err := json.Marshal(s)
+	require.NoError(t, err)
+
+	expected := `{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "object",
+		"properties": {
+			"root": {"$ref": "#/$defs/testNode"},
+			"nodes": {"type": ["array", "null"], "items": {"$ref": "#/$defs/testNode"}}
+		},
+		"required": ["root", "nodes"],
+		"$defs": {
+			"testNode": {
+				"type": "object",
+				"properties": {
+					"value": {"type": "string"},
+					"parent": {"anyOf": [{"$ref": "#/$defs/testNode"}, {"type": "null"}]}
+				},
+				"required": ["value", "parent"]
+			}
+		}
+	}`
+	require.JSONEq(t, expected, string(b))
+}
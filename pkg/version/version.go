@@ -63,7 +63,7 @@ func (v *Version[T]) Cmd(ch *cmdutils.Helper[T], cli string) *cobra.Command {
 		Use:    "version",
 		Hidden: true,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			if ch.Printer.Format() == printer.Human {
+			if ch.Printer.ResourceFormat() == printer.Human {
 				ch.Printer.Println(v.Format(cli))
 				return nil
 			}
@@ -78,5 +78,8 @@ func (v *Version[T]) Cmd(ch *cmdutils.Helper[T], cli string) *cobra.Command {
 		},
 	}
 
+	cmdutils.DeclareOutput[map[string]string](ch, cmd)
+	cmdutils.SkipValidation(cmd)
+
 	return cmd
 }
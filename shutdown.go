@@ -0,0 +1,37 @@
+// SPDX-License-Identifier: Apache-2.0
+
+package cmdutils
+
+import (
+	"context"
+	"errors"
+)
+
+// OnShutdown registers fn to be called when the command exits, after its run
+// function returns or once it is interrupted by a signal. Hooks are called in
+// the reverse order of their registration, like deferred calls, and ctx
+// expires at the end of the shutdown grace period.
+func (h *Helper[T]) OnShutdown(fn func(ctx context.Context) error) {
+	h.shutdownMu.Lock()
+	defer h.shutdownMu.Unlock()
+
+	h.shutdownHooks = append(h.shutdownHooks, fn)
+}
+
+// Shutdown calls the hooks registered with OnShutdown, most recent first, and
+// returns their errors. Every hook is called at most once.
+func (h *Helper[T]) Shutdown(ctx context.Context) error {
+	h.shutdownMu.Lock()
+	hooks := h.shutdownHooks
+	h.shutdownHooks = nil
+	h.shutdownMu.Unlock()
+
+	var errs []error
+	for i := len(hooks) - 1; i >= 0; i-- {
+		if err := hooks[i](ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	return errors.Join(errs...)
+}